@@ -5,23 +5,134 @@ import "errors"
 //TODO: Straightforward handling for API
 
 // API represents the cheesse API. All cheesse API methods are exported methods of this struct.
-type API struct{}
+type API struct {
+	workers *workerPool
+	output  outputOptions
+}
+
+// outputOptions configure how games are mapped to OutputGame.
+type outputOptions struct {
+	sortedPieceArrays bool
+	gameFields        outputGameFields // nil means all fields
+	stepGameFields    outputGameFields // nil means the same as gameFields
+	err               error            // If the options are invalid, every API call that returns games fails
+}
+
+// forSteps returns the options for mapping the games of steps that are not the last step of the mainline.
+func (o outputOptions) forSteps() outputOptions {
+	if o.stepGameFields != nil {
+		o.gameFields = o.stepGameFields
+	}
+	return o
+}
+
+// Option configures an API. Please supply options to New.
+type Option func(*API)
+
+// WithParallelActions makes the API calculate the actions of every game in parallel, using at most `workers`
+// goroutines at any given time across all calls to this API instance, besides the calling goroutines. When all of
+// them are busy, the calling goroutine calculates the actions itself, so calls never wait for each other.
+//
+// The resulting actions are the same, and in the same order, as when calculated sequentially, which is the default.
+// A number of workers smaller than 2 keeps the default. Each worker gets the moves of at least 8 pieces, as smaller
+// chunks of work are faster sequentially, so e.g. a player with fewer than 16 pieces never uses more than one worker.
+func WithParallelActions(workers int) Option {
+	return func(a *API) {
+		a.workers = nil
+		if workers >= 2 {
+			a.workers = newWorkerPool(workers)
+		}
+	}
+}
+
+// WithSortedPieceArrays makes every OutputGame also describe the pieces as arrays sorted by square, i.e.
+// `blackPieceList` and `whitePieceList`, besides the `blackPieces` and `whitePieces` maps. Please refer to
+// OutputGame's docs for details.
+func WithSortedPieceArrays() Option {
+	return func(a *API) {
+		a.output.sortedPieceArrays = true
+	}
+}
+
+// WithOutputGameFields makes the API calculate and serialise only the given fields of every OutputGame, by their
+// JSON names (e.g. `fenString`, `actions`), rather than all of them, which is the default. The rest are left empty,
+// and omitted from JSON. This makes responses much smaller, e.g. when parsing long games.
+//
+// Note that when `actions` is not among the fields, ParseGame and DoAction don't even calculate the actions of the
+// resulting game, which is by far the most expensive part.
+//
+// Unknown fields make every API call that returns games fail.
+func WithOutputGameFields(fields ...string) Option {
+	return func(a *API) {
+		var err error
+		a.output.gameFields, err = newOutputGameFields(fields)
+		if a.output.err == nil {
+			a.output.err = err
+		}
+	}
+}
+
+// WithStepGameFields works like WithOutputGameFields, but only for the games of OutputGameSteps, except for the
+// last step of the mainline, i.e. the final game, which follows WithOutputGameFields. For example, to get only the
+// FEN string of every step, but all fields of the final game:
+//
+//	api.New(api.WithStepGameFields("fenString"))
+//
+// Note that this only makes responses smaller, and doesn't save computation: parsing a match calculates the actions
+// of every step anyway, as they're needed to parse the action that follows.
+func WithStepGameFields(fields ...string) Option {
+	return func(a *API) {
+		var err error
+		a.output.stepGameFields, err = newOutputGameFields(fields)
+		if a.output.err == nil {
+			a.output.err = err
+		}
+	}
+}
+
+// New constructs an API, configured by the given options.
+func New(opts ...Option) API {
+	var a API
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
+}
 
-// New constructs an API.
-func New() API { return API{} }
+// WithOptions returns a copy of the API, further configured by the given options. The copy shares everything else,
+// e.g. the workers of WithParallelActions, so it's cheap to use it for a single call, e.g. per server request.
+func (a API) WithOptions(opts ...Option) API {
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
+}
 
 var (
 	errInvalidInputGame                    = errors.New("invalid input game: please supply a valid fenString or a board")
 	errAlgebraicSquareInvalidOrOutOfBounds = errors.New("invalid algebraic square: empty or out of bounds")
 	errInvalidPieceTypeName                = errors.New("invalid piece type name: please use one of {Queen|King|Bishop|Knight|Rook|Pawn} or empty string")
 	errInvalidActionForGivenGame           = errors.New("the specified action is invalid for the specified game")
+	errInvalidDrawOfferedBy                = errors.New("invalid drawOfferedBy: please use one of {Black|White} or empty string")
+	errInvalidPerftDepth                   = errors.New("invalid perft depth: please use a number greater than or equal to zero")
+	errInvalidUCI                          = errors.New("invalid uci: please use a UCI move, e.g. e2e4 or e7e8q")
+	errInvalidSAN                          = errors.New("invalid san: please use a move in Standard Algebraic Notation, e.g. Nbd7, exd6 e.p., O-O-O+ or e8=Q#")
+	errAmbiguousSAN                        = errors.New("ambiguous san")
+	errIllegalSAN                          = errors.New("illegal san")
+	errInvalidNotationCharacteristics      = errors.New("invalid notation characteristics")
+	errInvalidPositionHistory              = errors.New("invalid position history: please use the positionHistory of an OutputGame, i.e. keys of 16 hexadecimal digits")
 )
 
 // DefaultGame returns the initial game of chess, with all pieces on their default positions
 // and before any action has taken place.
 func (a API) DefaultGame() OutputGame {
-	var defaultGame, _ = newGameFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
-	return mapGameToOutputGame(defaultGame)
+	var defaultGame, _ = parseFEN(defaultFEN)
+	defaultGame.workers = a.workers
+	defaultGame = defaultGame.calculateFlags()
+	if a.output.gameFields.hasActions() {
+		defaultGame = defaultGame.withActions()
+	}
+	return mapGameToOutputGame(defaultGame, a.output)
 }
 
 // ParseGame takes any valid input game and parses it, returning an OutputGame, which contains
@@ -32,11 +143,14 @@ func (a API) DefaultGame() OutputGame {
 //
 // Please refer to InputGame's and OutputGame's docs for format details.
 func (a API) ParseGame(game InputGame) (OutputGame, error) {
-	parsedGame, err := a.parseGame(game)
+	parsedGame, err := a.parseGameWithoutActions(game)
 	if err != nil {
 		return OutputGame{}, err
 	}
-	return mapGameToOutputGame(parsedGame), nil
+	if a.output.gameFields.hasActions() {
+		parsedGame = parsedGame.withActions()
+	}
+	return mapGameToOutputGame(parsedGame, a.output), nil
 }
 
 // DoAction takes any valid input game and any valid input action, parses them and attempts
@@ -57,32 +171,208 @@ func (a API) DoAction(game InputGame, action InputAction) (OutputGame, OutputAct
 	if err != nil {
 		return OutputGame{}, OutputAction{}, err
 	}
-	return mapGameToOutputGame(parsedGame.doAction(parsedAction)), mapInternalActionToAction(parsedAction), nil
+	newGame := parsedGame.doActionWithoutActions(parsedAction)
+	if a.output.gameFields.hasActions() {
+		newGame = newGame.withActions()
+	}
+	return mapGameToOutputGame(newGame, a.output), mapInternalActionToAction(parsedGame, parsedAction, threatenSuffix(newGame)), nil
 }
 
 // ParseNotation takes any valid input game and a string representing a match in some
 // notation, parses them and attempts to play the match starting from the supplied
-// game. If it fails, it returns an error describing the problem.
+// game. If it fails, it returns an error describing the problem; if the problem is
+// in `notationString`, it's a NotationError describing where it happened, e.g. so
+// that an editor can underline the mistake.
 //
-// If parsing the match succeeds, it returns the parsed initial game and a list of
-// steps, one per action in the `notationString`.
+// If parsing the match succeeds, it returns the parsed initial game, a list of
+// steps, one per action in the `notationString`, and the notation in which the
+// match was parsed, including the style detected while parsing it.
 //
 // An example `notationString` (Scholar's mate):
 //
 // `1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qxf7#`
 //
-// At the moment, only Algebraic Notation is supported, but support for most
-// notations is planned at a later release.
+// `notation` must be one of: `{algebraic|lan|uci|descriptive|iccf|auto}`, or an
+// empty string for `algebraic`. `auto` tries every notation, in that order, and
+// the first one that parses the whole match wins. If none does, the error is the
+// one of the notation that parsed the most actions.
+//
+// In Algebraic Notation, pieces may be named in English, German (e.g. `Sf3`),
+// Spanish, French or Russian, or with figurines (e.g. `♘f3`); the language is
+// detected as the match is parsed, and mixing languages is an error.
+//
+// `characteristics` is the style the match must follow, e.g. `{"castlingSymbol": "O",
+// "checkSymbol": "+", "checkmateSymbol": "#"}` rejects `0-0`, and also checks or
+// checkmates without their symbol. Unset characteristics are learnt from the match
+// instead: once e.g. `0-0` is found, `O-O` is an error.
 //
-// Please refer to InputGame's, OutputGame's and OutputGameStep's docs for format
-// details.
-func (a API) ParseNotation(game InputGame, notationString string) (OutputGame, []OutputGameStep, error) {
+// Please refer to InputGame's, OutputGame's, OutputGameStep's and OutputNotation's
+// docs for format details.
+func (a API) ParseNotation(game InputGame, notationString string, notation string, characteristics NotationCharacteristics) (OutputGame, []OutputGameStep, OutputNotation, error) {
 	parsedGame, err := a.parseGame(game)
 	if err != nil {
+		return OutputGame{}, []OutputGameStep{}, OutputNotation{}, err
+	}
+	initialCharacteristics, err := mapNotationCharacteristicsToCharacteristics(characteristics)
+	if err != nil {
+		return OutputGame{}, []OutputGameStep{}, OutputNotation{}, err
+	}
+
+	notation, gameSteps, ch, err := parseNotation(parsedGame, notationString, notation, initialCharacteristics)
+	if err == errUnknownInputNotation {
+		return OutputGame{}, []OutputGameStep{}, OutputNotation{}, err
+	}
+	outputNotation := OutputNotation{Notation: notation, Characteristics: mapCharacteristicsToNotationCharacteristics(ch)}
+	return mapGameToOutputGame(parsedGame, a.output), mapGameStepsToOutputGameSteps(parsedGame, gameSteps, a.output), outputNotation, err
+}
+
+// ConvertNotation takes any valid input game and a string representing a match in the
+// `fromNotation` notation, parses them and attempts to play the match starting from the
+// supplied game, exactly like ParseNotation does. Then, it renders every action in the
+// `toNotation` notation.
+//
+// If converting the match succeeds, it returns the parsed initial game and a list of
+// steps, one per action in the `notationString`, where each `actionString` is the
+// converted action. If parsing fails midway, the steps converted so far are returned
+// together with a NotationError.
+//
+// `toNotation` must be one of:
+// `{algebraic|algebraic_de|algebraic_es|algebraic_fr|algebraic_ru|fan|lan|uci|descriptive|iccf}`,
+// where the `algebraic_*` ones name the pieces in German, Spanish, French or Russian,
+// `fan` is Figurine Algebraic Notation (e.g. `♘f3`) and `iccf` is ICCF Numeric
+// Notation (e.g. `7163`).
+//
+// `fromNotation` is one of the notations of ParseNotation, including `auto`, and
+// defaults to Algebraic Notation if it's an empty string.
+//
+// `toCharacteristics` is the style of the converted actions, e.g. `{"castlingSymbol":
+// "0", "checkSymbol": "ch"}` renders `0-0ch`. Passing the characteristics returned by
+// ParseNotation reproduces the conventions of the source match. At the moment, only
+// `algebraic` supports styles; the other notations ignore it.
+//
+// Please refer to InputGame's, OutputGame's, OutputGameStep's and
+// NotationCharacteristics's docs for format details.
+func (a API) ConvertNotation(game InputGame, notationString string, fromNotation string, toNotation string, toCharacteristics NotationCharacteristics) (OutputGame, []OutputGameStep, error) {
+	emit, ok := notationEmitters[toNotation]
+	if !ok {
+		return OutputGame{}, []OutputGameStep{}, errUnknownNotation
+	}
+	style, err := mapNotationCharacteristicsToCharacteristics(toCharacteristics)
+	if err != nil {
+		return OutputGame{}, []OutputGameStep{}, err
+	}
+	if styledEmit, ok := styledNotationEmitters[toNotation]; ok {
+		emit = styledEmit(style)
+	}
+	parsedGame, err := a.parseGame(game)
+	if err != nil {
+		return OutputGame{}, []OutputGameStep{}, err
+	}
+
+	_, gameSteps, _, err := parseNotation(parsedGame, notationString, fromNotation, characteristics{})
+	if err == errUnknownInputNotation {
 		return OutputGame{}, []OutputGameStep{}, err
 	}
+	return mapGameToOutputGame(parsedGame, a.output), mapGameStepsToOutputGameSteps(parsedGame, emitSteps(emit, parsedGame, gameSteps), a.output), err
+}
 
-	// TODO at the moment there only exists an algebraic notation parser
-	gameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(parsedGame, notationString)
-	return mapGameToOutputGame(parsedGame), mapGameStepsToOutputGameSteps(gameSteps), err
+// ParsePGN takes a string representing a single match in Portable Game Notation,
+// parses it and attempts to play it, starting from the game described by its `FEN`
+// tag, or from the default game if it doesn't have one. If it fails, it returns a
+// PGNError describing the problem and where in `pgnString` it happened.
+//
+// Tag pairs, brace and rest-of-line comments, Numeric Annotation Glyphs, move suffix
+// annotations (e.g. `!?`), recursive variations and game termination markers are
+// supported.
+//
+// An example `pgnString`:
+//
+// `[Event "Casual game"]\n[Result "1-0"]\n\n1. e4 e5 2. Bc4 {Italian} (2. Nf3) 2... Nc6 3. Qh5 Nf6?? 4. Qxf7# 1-0`
+//
+// To parse a database with many games, please use a PGNScanner.
+//
+// Please refer to OutputPGN's and OutputGameStep's docs for format details.
+func (a API) ParsePGN(pgnString string) (OutputPGN, error) {
+	if a.output.err != nil {
+		return OutputPGN{}, a.output.err
+	}
+	pg, err := parsePGN(pgnString)
+	if err != nil {
+		return OutputPGN{}, newPGNError(pgnString, 0, 1, err)
+	}
+	return mapPGNGameToOutputPGN(pg, a.output), nil
+}
+
+// ExportPGN takes any valid input game and a list of valid input actions, plays the
+// actions starting from the supplied game and serialises the match in Portable Game
+// Notation, in export format. If any of the actions is invalid, an error will be
+// returned.
+//
+// The Seven Tag Roster is always present, in its standard order, using the values
+// in `tags` when supplied, and `?` otherwise. The rest of `tags` follow. When the
+// supplied game is not the default game, the `SetUp` and `FEN` tags are added.
+//
+// The actions are rendered in Standard Algebraic Notation, and the `Result` tag and
+// game termination marker are derived from the game after the last action, i.e.
+// checkmate, resignation, draw, or `*` if the game is not over.
+//
+// Please refer to InputGame's, InputAction's and PGNTag's docs for format details.
+func (a API) ExportPGN(game InputGame, actions []InputAction, tags []PGNTag) (string, error) {
+	parsedGame, err := a.parseGame(game)
+	if err != nil {
+		return "", err
+	}
+	pg := pgnGame{tags: mapPGNTagsToInternalTags(tags), initialGame: parsedGame}
+	currentGame := parsedGame
+	for _, inputAction := range actions {
+		parsedAction, err := a.parseAction(inputAction, currentGame)
+		if err != nil {
+			return "", err
+		}
+		currentGame = currentGame.doAction(parsedAction)
+		pg.steps = append(pg.steps, pgnStep{gameStep: gameStep{a: parsedAction, g: currentGame}})
+	}
+	return writePGN(pg), nil
+}
+
+// ExportPGNSteps works like ExportPGN, but takes a list of steps, like the ones returned
+// by ParseNotation or ParsePGN, rather than a list of input actions. The comments, NAGs
+// and variations of the steps are also serialised.
+//
+// Please refer to ExportPGN's and OutputGameStep's docs for format details.
+func (a API) ExportPGNSteps(game InputGame, steps []OutputGameStep, tags []PGNTag) (string, error) {
+	parsedGame, err := a.parseGame(game)
+	if err != nil {
+		return "", err
+	}
+	pgnSteps, err := a.parseOutputGameSteps(steps, parsedGame)
+	if err != nil {
+		return "", err
+	}
+	return writePGN(pgnGame{tags: mapPGNTagsToInternalTags(tags), initialGame: parsedGame, steps: pgnSteps}), nil
+}
+
+// Perft takes any valid input game and a depth, and walks the tree of all valid
+// actions that move pieces up to that depth, counting the games reached after
+// exactly `depth` actions, together with how many of them were reached by a
+// capture, en passant, castling or promotion, and how many are check or
+// checkmate. It also returns a breakdown of these counts per valid action of
+// the supplied game (i.e. "divide").
+//
+// It's meant for verifying move generators against well-known results, so
+// resigning, draws, etc. are not walked, and games are not considered over by
+// any rule other than checkmate and stalemate. Note that the walk grows
+// exponentially with `depth`.
+//
+// Please refer to InputGame's and OutputPerft's docs for format details.
+func (a API) Perft(game InputGame, depth int) (OutputPerft, error) {
+	if depth < 0 {
+		return OutputPerft{}, errInvalidPerftDepth
+	}
+	parsedGame, err := a.parseGame(game)
+	if err != nil {
+		return OutputPerft{}, err
+	}
+	counts, divide := parsedGame.perft(depth)
+	return mapPerftToOutputPerft(parsedGame, counts, divide), nil
 }
@@ -1,5 +1,10 @@
 package api
 
+import (
+	"fmt"
+	"strings"
+)
+
 // InputGame is the input interface to supply a chess game.
 //
 // There are 3 different ways to supply the chess game:
@@ -11,23 +16,71 @@ package api
 // 3. via empty struct: assumes the defaultGame.
 //
 // If you supply both the `fenString` and the `board`, `board` is ignored silently.
+//
+// Because FEN notation doesn't describe draw offers, a pending draw offer is
+// supplied separately in `drawOfferedBy`, which must be one of `{Black|White}`,
+// or an empty string if there's no pending draw offer.
+//
+// Likewise, the previous positions of the game are supplied separately in
+// `positionHistory`, so that repetitions are detected across API calls. It's the
+// `positionHistory` of the OutputGame of the previous call, i.e. the keys of the
+// previous positions as 16 hexadecimal digits, oldest first. Only the last
+// `halfMoveClock` keys are considered, as positions before the last capture or
+// pawn movement can't be repeated. It may be empty.
 type InputGame struct {
-	FENString string `json:"fenString"`
-	Board     Board  `json:"board"`
+	FENString       string   `json:"fenString"`
+	Board           Board    `json:"board"`
+	DrawOfferedBy   string   `json:"drawOfferedBy"`
+	PositionHistory []string `json:"positionHistory"`
 }
 
 // InputAction is the input interface to supply a chess action.
 //
-// - `fromSquare` and `toSquare` are required, and must be board cells described in
-// Algebraic Notation (e.g. `e2`). Note that `a1` is where the White Queen's Rook starts.
+// - `fromSquare` and `toSquare` are required (unless `uci` or `san` are
+// supplied), and must be board cells described in Algebraic Notation (e.g.
+// `e2`). Note that `a1` is where the White Queen's Rook starts.
 //
 // - `promotionPieceType` is only required if the action is a promotion.
 //
 // - `promotionPieceType` must be one of: `{Queen|King|Bishop|Knight|Rook|Pawn}`.
+//
+// - `uci` may be supplied instead of `fromSquare`, `toSquare` and
+// `promotionPieceType`, as a move in UCI notation (e.g. `e2e4` or `e7e8q`).
+// Castling may be described either by the King's destination (e.g. `e1g1`) or
+// as the King taking its own Rook (e.g. `e1h1`), as in Chess960. When
+// supplied, the other three fields are ignored.
+//
+// - `san` may also be supplied instead, as a move in Standard Algebraic Notation
+// (e.g. `Nbd7`, `exd6 e.p.`, `O-O-O+` or `e8=Q#`). If the move is ambiguous or
+// illegal, the error lists the candidate actions. When supplied (and `uci`
+// isn't), the squares and `promotionPieceType` are ignored.
+//
+// - `isResign`, `isClaimDraw`, `isTimeout`, `isOfferDraw`, `isAcceptDraw`,
+// `isDeclineDraw` and `isAbort` are for the actions that don't move any piece, and
+// are done by the player to move. When any is true, the squares are ignored:
+//
+//   - `isResign`: the player resigns.
+//   - `isClaimDraw`: the player claims a draw, only if the game's `canClaimDraw` is true.
+//   - `isTimeout`: the player runs out of time.
+//   - `isOfferDraw`: the player offers a draw, and may then make a move. The offer
+//     is pending until the opponent responds to it or makes a move.
+//   - `isAcceptDraw` and `isDeclineDraw`: the player responds to the opponent's
+//     pending draw offer. Accepting it ends the game in a draw by agreement.
+//   - `isAbort`: the player aborts the game, only before both players have made
+//     their first move.
 type InputAction struct {
 	FromSquare         string `json:"fromSquare"`
 	ToSquare           string `json:"toSquare"`
 	PromotionPieceType string `json:"promotionPieceType"`
+	UCI                string `json:"uci"`
+	SAN                string `json:"san"`
+	IsResign           bool   `json:"isResign"`
+	IsClaimDraw        bool   `json:"isClaimDraw"`
+	IsTimeout          bool   `json:"isTimeout"`
+	IsOfferDraw        bool   `json:"isOfferDraw"`
+	IsAcceptDraw       bool   `json:"isAcceptDraw"`
+	IsDeclineDraw      bool   `json:"isDeclineDraw"`
+	IsAbort            bool   `json:"isAbort"`
 }
 
 // Board is one of the input interfaces to supply a chess game.
@@ -61,7 +114,18 @@ type Board struct {
 //
 // - `fenString` represents the chess game as a FEN Notation string.
 //
-// - `actions` is the exhaustive list of actions that can follow from this game.
+// - `actions` is the exhaustive list of actions that move pieces and can follow
+// from this game, in a deterministic order: sorted by the square of the piece,
+// then by `toSquare`, then by `promotionPieceType` (Queen, Rook, Bishop, Knight).
+// Squares are sorted as in `fenString` and `board`, i.e. `a8`, `b8`, ..., `h8`,
+// `a7`, ..., `h1`.
+//
+// - `nonMoveActions` is the list of actions that don't move pieces and can follow
+// from this game, in this order: `isResign`, `isTimeout`, `isOfferDraw` (or
+// `isAcceptDraw` and then `isDeclineDraw`), `isAbort` and `isClaimDraw`, when
+// available. Both lists are empty when the game is over. `isAbort` is only
+// available before both players have made their first move, i.e. in the initial
+// position, or after White's first move.
 //
 // - `enPassantTargetSquare` is a board cell described in Algebraic Notation
 // (e.g. `e2`). Note that `a1` is where the White Queen's Rook starts. If there's
@@ -71,6 +135,11 @@ type Board struct {
 // are represented in Algebraic Notation (e.g `e2`), and the piece names are one
 // of `{Queen|King|Bishop|Knight|Rook|Pawn}`.
 //
+// - `blackPieceList` and `whitePieceList` describe the same pieces as
+// `blackPieces` and `whitePieces`, but as arrays sorted by square, in the same
+// order as `actions`. They are only present if the API was constructed with the
+// WithSortedPieceArrays option.
+//
 // - `blackKing` and `whiteKing` are the cells where the Kings are located. The
 // cells are represented in Algebraic Notation (e.g `e2`).
 //
@@ -82,12 +151,69 @@ type Board struct {
 // represented in Algebraic Notation (e.g `e2`). To find out which piece is in a
 // cell, inspect `blackPieces` and `whitePieces`.
 //
+// - `repetitionCount` is the number of times that the current position has
+// occurred in the game, including now. Positions are the same if the pieces are
+// placed in the same way, it's the same player's turn, and the castling rights and
+// possible en passant captures are the same. Only positions after the last capture
+// or pawn movement are considered, and only if they were reached by applying actions
+// or supplied in the input game's `positionHistory` (i.e. otherwise, positions
+// before a supplied FEN string or board are unknown).
+//
+// - `positionHistory` are the keys of the previous positions considered by
+// `repetitionCount`, as 16 hexadecimal digits, oldest first. Supply it in the
+// next call's input game to keep counting repetitions, e.g. to claim a draw by
+// threefold repetition after a few DoAction calls. A key is the position's
+// `positionHash`, except that the en passant file doesn't count if no en passant
+// capture is possible.
+//
+// - `isThreefoldRepetition` is true when the current position has occurred at least
+// three times, and `isFivefoldRepetition` when it has occurred at least five times.
+// As per FIDE rules, fivefold repetition ends the game in a draw.
+//
+// - `canClaimDraw` is true when the player to move may claim a draw, by doing the
+// claim draw action (i.e. `isClaimDraw`), which is then among `nonMoveActions`.
+// `claimDrawReasons` lists why, and is a subset of `{fiftyMove|repetition}`: 50
+// moves by each player without captures or pawn movements, or threefold
+// repetition. Note that after 75 moves, or on fivefold repetition, the game ends
+// in a draw automatically, without claiming it.
+//
+// - `canWhiteWinOnTime` and `canBlackWinOnTime` are true when the player could
+// checkmate the opponent by any sequence of legal actions. A player whose opponent
+// runs out of time (i.e. the `isTimeout` action) only wins if this is true;
+// otherwise it's a draw. When it's false for both players, the game ends in a draw
+// by insufficient material (e.g. King vs King and Knight).
+//
+// - `gameOverReason` is one of `{checkmate|resignation|stalemate|fiftyMove|
+// seventyFiveMove|repetition|insufficientMaterial|agreement|timeout|aborted}`, and
+// represents why the game is over, when `isGameOver` is true. Empty string
+// otherwise.
+//
+// - `result` is one of `{1-0|0-1|1/2-1/2|*}`, as in the game termination marker of
+// Portable Game Notation: White won, Black won, draw (including stalemate), or the
+// game is not over (or was aborted).
+//
+// - `drawOfferedBy` is one of `{Black|White}`, the player who offered a draw that
+// is still pending, or an empty string if there's no pending draw offer.
+//
+// - `positionHash` is the 64-bit Zobrist key of the position, as 16 hexadecimal
+// digits (e.g. `463b96181691fc9c` for the default game). It's the same for
+// games with the same pieces, turn, castling rights and en passant file, and is
+// compatible with the keys of Polyglot opening books. Note that, as in Polyglot,
+// the en passant file only counts if a Pawn of the player to move stands next to
+// the Pawn that just moved two squares.
+//
+// By default, all fields are calculated. With the WithOutputGameFields and
+// WithStepGameFields options, only the selected fields are calculated and
+// serialised to JSON; the rest keep their zero values. Use IsFieldSelected to
+// tell an unselected field from a zero value.
+//
 // Because OutputGame is a superset of InputGame, you may supply an OutputGame to
 // any API call that expects an InputGame.
 type OutputGame struct {
 	FENString               string            `json:"fenString"`
 	Board                   Board             `json:"board"`
 	Actions                 []OutputAction    `json:"actions"`
+	NonMoveActions          []OutputAction    `json:"nonMoveActions"`
 	CanWhiteCastle          bool              `json:"canWhiteCastle"`
 	CanWhiteKingsideCastle  bool              `json:"canWhiteKingsideCastle"`
 	CanWhiteQueensideCastle bool              `json:"canWhiteQueensideCastle"`
@@ -101,6 +227,8 @@ type OutputGame struct {
 	MoveNumber              int               `json:"moveNumber"`
 	BlackPieces             map[string]string `json:"blackPieces"`
 	WhitePieces             map[string]string `json:"whitePieces"`
+	BlackPieceList          []OutputPiece     `json:"blackPieceList,omitempty"`
+	WhitePieceList          []OutputPiece     `json:"whitePieceList,omitempty"`
 	BlackKing               string            `json:"blackKing"`
 	WhiteKing               string            `json:"whiteKing"`
 	IsCheck                 bool              `json:"isCheck"`
@@ -110,6 +238,31 @@ type OutputGame struct {
 	IsGameOver              bool              `json:"isGameOver"`
 	GameOverWinner          string            `json:"gameOverWinner"`
 	InCheckBy               []string          `json:"inCheckBy"`
+	RepetitionCount         int               `json:"repetitionCount"`
+	IsThreefoldRepetition   bool              `json:"isThreefoldRepetition"`
+	IsFivefoldRepetition    bool              `json:"isFivefoldRepetition"`
+	CanClaimDraw            bool              `json:"canClaimDraw"`
+	ClaimDrawReasons        []string          `json:"claimDrawReasons"`
+	GameOverReason          string            `json:"gameOverReason"`
+	CanWhiteWinOnTime       bool              `json:"canWhiteWinOnTime"`
+	CanBlackWinOnTime       bool              `json:"canBlackWinOnTime"`
+	Result                  string            `json:"result"`
+	DrawOfferedBy           string            `json:"drawOfferedBy"`
+	PositionHash            string            `json:"positionHash"`
+	PositionHistory         []string          `json:"positionHistory"`
+
+	fields outputGameFields // Selected fields; nil means all
+}
+
+// OutputPiece is the output interface that describes a piece on the board.
+//
+// - `square` is the board cell where the piece is, described in Algebraic
+// Notation (e.g. `e2`).
+//
+// - `pieceType` is one of `{Queen|King|Bishop|Knight|Rook|Pawn}`.
+type OutputPiece struct {
+	Square    string `json:"square"`
+	PieceType string `json:"pieceType"`
 }
 
 // OutputAction is the output interface that describes a chess action.
@@ -131,6 +284,15 @@ type OutputGame struct {
 // - `capturedPieceType` is one of `{Queen|King|Bishop|Knight|Rook|Pawn}`,
 // and represents the piece that was captured, if the action is a capture.
 // If the action is not a capture, it's an empty string.
+//
+// - `uci` is the move in UCI notation (e.g. `e2e4`, `e7e8q` or `e1g1` for
+// castling). It's an empty string for actions that don't move any piece.
+//
+// - `san` and `lan` are the move in Standard and Long Algebraic Notation (e.g.
+// `Nbxd7+` and `Nb8xd7+`), including the `+` or `#` suffix if the move checks or
+// checkmates. SAN is minimally disambiguated, as per the other actions of the
+// game the move follows from. Both are empty strings for actions that don't
+// move any piece.
 type OutputAction struct {
 	FromPieceOwner     string `json:"fromPieceOwner"`
 	FromPieceType      string `json:"fromPieceType"`
@@ -138,6 +300,12 @@ type OutputAction struct {
 	ToSquare           string `json:"toSquare"`
 	IsCapture          bool   `json:"isCapture"`
 	IsResign           bool   `json:"isResign"`
+	IsClaimDraw        bool   `json:"isClaimDraw"`
+	IsTimeout          bool   `json:"isTimeout"`
+	IsOfferDraw        bool   `json:"isOfferDraw"`
+	IsAcceptDraw       bool   `json:"isAcceptDraw"`
+	IsDeclineDraw      bool   `json:"isDeclineDraw"`
+	IsAbort            bool   `json:"isAbort"`
 	IsPromotion        bool   `json:"isPromotion"`
 	IsEnPassant        bool   `json:"isEnPassant"`
 	IsEnPassantCapture bool   `json:"isEnPassantCapture"`
@@ -146,6 +314,9 @@ type OutputAction struct {
 	IsQueensideCastle  bool   `json:"isQueensideCastle"`
 	PromotionPieceType string `json:"promotionPieceType"`
 	CapturedPieceType  string `json:"capturedPieceType"`
+	UCI                string `json:"uci"`
+	SAN                string `json:"san"`
+	LAN                string `json:"lan"`
 }
 
 // OutputGameStep is the output interface that describes a step in a parsed
@@ -165,18 +336,169 @@ type OutputAction struct {
 // `actionString`.
 //
 // - `game` represents the chess game AFTER applying the inferred action.
+//
+// - `comments`, `nags` and `variations` are only present on steps parsed from
+// PGN. `nags` are Numeric Annotation Glyphs (e.g. `1` for `!`), including the
+// ones written as move suffix annotations. Each variation is a list of steps
+// which is an alternative to this step, so it starts from the game BEFORE
+// applying this step's action.
 type OutputGameStep struct {
-	Game         OutputGame   `json:"game"`
+	Game         OutputGame         `json:"game"`
+	Action       OutputAction       `json:"action"`
+	ActionString string             `json:"actionString"`
+	Comments     []string           `json:"comments,omitempty"`
+	NAGs         []int              `json:"nags,omitempty"`
+	Variations   [][]OutputGameStep `json:"variations,omitempty"`
+}
+
+// OutputNotation is the output interface that describes the notation in which
+// a match was parsed.
+//
+// - `notation` is one of `{algebraic|lan|uci|descriptive|iccf}`. When parsing
+// with the `auto` notation, it's the notation that was detected.
+//
+// - `characteristics` is the style of the notation string, as detected while
+// parsing it. Please refer to NotationCharacteristics.
+type OutputNotation struct {
+	Notation        string                  `json:"notation"`
+	Characteristics NotationCharacteristics `json:"characteristics"`
+}
+
+// NotationCharacteristics describes the style of a notation string. Every
+// field is omitted when it's unknown, e.g. `checkSymbol` is omitted if the
+// match has no checks. As an input, unset fields are not enforced.
+//
+// - `checkSymbol` and `checkmateSymbol` are the suffixes of the actions that
+// check or checkmate, e.g. `+` or `ch`, and `#` or `mate`.
+//
+// - `castlingSymbol` is `O` for `O-O`, or `0` for `0-0`.
+//
+// - `promotionSymbol` is what precedes the promoted piece, e.g. `=` for
+// `e8=Q`, `(` for `e8(Q)`, or an empty string for `e8Q`.
+//
+// - `endGameSymbol` is one of `{numbers|resigns|color resigns}`.
+//
+// - `fullMoveDot` is true if full move numbers are followed by a dot, e.g.
+// `1. e4`, and false if they aren't, e.g. `1 e4`.
+//
+// - `newlineAsFullMoveSeparator` is true if every full move is on its own line,
+// and false if full moves are separated by spaces.
+//
+// - `pieceLetters` are the languages whose piece letters the match is
+// consistent with, out of `{en|de|es|fr|ru|figurine}`, e.g. `["en", "de"]` if
+// only the King was named.
+type NotationCharacteristics struct {
+	CheckSymbol                *string  `json:"checkSymbol,omitempty"`
+	CheckmateSymbol            *string  `json:"checkmateSymbol,omitempty"`
+	CastlingSymbol             *string  `json:"castlingSymbol,omitempty"`
+	PromotionSymbol            *string  `json:"promotionSymbol,omitempty"`
+	EndGameSymbol              *string  `json:"endGameSymbol,omitempty"`
+	FullMoveDot                *bool    `json:"fullMoveDot,omitempty"`
+	NewlineAsFullMoveSeparator *bool    `json:"newlineAsFullMoveSeparator,omitempty"`
+	PieceLetters               []string `json:"pieceLetters,omitempty"`
+}
+
+// PGNTag is a tag pair of a game in Portable Game Notation, e.g. `[Event "F/S Return Match"]`.
+type PGNTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// OutputPGN is the output interface that describes a game parsed from Portable
+// Game Notation.
+//
+// - `tags` are the tag pairs, in the order they were supplied.
+//
+// - `game` is the initial game, which is the default game unless a `FEN` tag
+// is supplied.
+//
+// - `outputGameSteps` is the mainline of the game. Please refer to the docs of
+// OutputGameStep for its format, including comments and variations.
+//
+// - `comments` are the comments that precede the first action of the game.
+//
+// - `result` is the game termination marker, one of `{1-0|0-1|1/2-1/2|*}`, or
+// an empty string if it's missing.
+type OutputPGN struct {
+	Tags            []PGNTag         `json:"tags"`
+	Game            OutputGame       `json:"game"`
+	OutputGameSteps []OutputGameStep `json:"outputGameSteps"`
+	Comments        []string         `json:"comments"`
+	Result          string           `json:"result"`
+}
+
+// PerftCounts are the statistics of the games reached by a perft walk after
+// exactly `depth` actions, i.e. its leaf games.
+//
+// - `nodes` is the number of leaf games.
+//
+// - `captures`, `enPassants`, `castles` and `promotions` are the number of
+// leaf games whose last action was of that kind. Note that `captures`
+// includes en passant captures.
+//
+// - `checks` and `checkmates` are the number of leaf games in which the King
+// of the player whose turn it is is in check, or checkmated.
+type PerftCounts struct {
+	Nodes      int `json:"nodes"`
+	Captures   int `json:"captures"`
+	EnPassants int `json:"enPassants"`
+	Castles    int `json:"castles"`
+	Promotions int `json:"promotions"`
+	Checks     int `json:"checks"`
+	Checkmates int `json:"checkmates"`
+}
+
+// OutputPerft is the output interface that describes the result of a perft
+// walk, which counts all games reachable from a game by a number of actions
+// that move pieces. It's useful to verify move generators.
+//
+// - The counts of all leaf games are embedded. Please refer to PerftCounts.
+//
+// - `divide` has the counts of the leaf games reached from each valid action
+// of the supplied game, sorted by `actionString`.
+type OutputPerft struct {
+	PerftCounts
+	Divide []OutputPerftDivide `json:"divide"`
+}
+
+// OutputPerftDivide is the output interface that describes the counts of the
+// leaf games of a perft walk that are reached from one valid action of the
+// supplied game.
+//
+// - `action` is the action, and `actionString` is the action in UCI notation
+// (e.g. `e2e4`).
+//
+// - The counts of the leaf games are embedded. Please refer to PerftCounts.
+type OutputPerftDivide struct {
 	Action       OutputAction `json:"action"`
 	ActionString string       `json:"actionString"`
+	PerftCounts
 }
 
-func mapGameToOutputGame(g game) OutputGame {
-	var o OutputGame
+func mapGameToOutputGame(g game, opts outputOptions) OutputGame {
+	var (
+		o   = OutputGame{fields: opts.gameFields}
+		has = opts.gameFields.has
+	)
 
-	o.FENString = g.toFEN()
-	o.Board = mapInternalBoardToBoard(g.toBoard())
-	o.Actions = make([]OutputAction, len(g.actions))
+	if has("fenString") {
+		o.FENString = g.toFEN()
+	}
+	if has("board") {
+		o.Board = mapInternalBoardToBoard(g.toBoard())
+	}
+	if has("actions") || has("nonMoveActions") {
+		o.Actions = []OutputAction{}
+		o.NonMoveActions = []OutputAction{}
+		suffixes := g.threatenSuffixes()
+		for i, a := range g.actions {
+			if a.isMove() {
+				o.Actions = append(o.Actions, mapInternalActionToAction(g, a, suffixes[i]))
+			} else {
+				o.NonMoveActions = append(o.NonMoveActions, mapInternalActionToAction(g, a, suffixes[i]))
+			}
+		}
+	}
 	o.CanWhiteCastle = g.canWhiteCastle
 	o.CanWhiteKingsideCastle = g.canWhiteKingsideCastle
 	o.CanWhiteQueensideCastle = g.canWhiteQueensideCastle
@@ -187,9 +509,28 @@ func mapGameToOutputGame(g game) OutputGame {
 	o.FullMoveNumber = g.fullMoveNumber
 	o.IsLastMoveEnPassant = g.isLastMoveEnPassant
 	o.EnPassantTargetSquare = ""
+	if g.isLastMoveEnPassant {
+		o.EnPassantTargetSquare = g.enPassantTargetSquare.toAlgebraic()
+	}
 	o.MoveNumber = g.moveNumber
-	o.BlackPieces = make(map[string]string, len(g.pieces[colorBlack]))
-	o.WhitePieces = make(map[string]string, len(g.pieces[colorWhite]))
+	if has("blackPieces") {
+		o.BlackPieces = make(map[string]string, len(g.pieces[colorBlack]))
+		for sq, p := range g.pieces[colorBlack] {
+			o.BlackPieces[sq.toAlgebraic()] = p.pieceType.String()
+		}
+	}
+	if has("whitePieces") {
+		o.WhitePieces = make(map[string]string, len(g.pieces[colorWhite]))
+		for sq, p := range g.pieces[colorWhite] {
+			o.WhitePieces[sq.toAlgebraic()] = p.pieceType.String()
+		}
+	}
+	if opts.sortedPieceArrays && has("blackPieceList") {
+		o.BlackPieceList = mapPiecesToSortedOutputPieces(g.pieces[colorBlack])
+	}
+	if opts.sortedPieceArrays && has("whitePieceList") {
+		o.WhitePieceList = mapPiecesToSortedOutputPieces(g.pieces[colorWhite])
+	}
 	o.BlackKing = g.kings[colorBlack].xy.toAlgebraic()
 	o.WhiteKing = g.kings[colorWhite].xy.toAlgebraic()
 	o.IsCheck = g.isCheck
@@ -199,30 +540,46 @@ func mapGameToOutputGame(g game) OutputGame {
 	o.IsGameOver = g.isGameOver
 	o.GameOverWinner = g.gameOverWinner.String()
 	o.InCheckBy = make([]string, len(g.inCheckBy))
-
-	for i := range g.actions {
-		o.Actions[i] = mapInternalActionToAction(g.actions[i])
-	}
-
-	if g.isLastMoveEnPassant {
-		o.EnPassantTargetSquare = g.enPassantTargetSquare.toAlgebraic()
+	for i := range g.inCheckBy {
+		o.InCheckBy[i] = g.inCheckBy[i].xy.toAlgebraic()
 	}
-
-	for sq, p := range g.pieces[colorBlack] {
-		o.BlackPieces[sq.toAlgebraic()] = p.pieceType.String()
+	o.RepetitionCount = g.repetitionCount
+	o.IsThreefoldRepetition = g.isThreefoldRepetition
+	o.IsFivefoldRepetition = g.isFivefoldRepetition
+	o.CanClaimDraw = g.canClaimDraw
+	o.ClaimDrawReasons = make([]string, len(g.claimDrawReasons))
+	for i := range g.claimDrawReasons {
+		o.ClaimDrawReasons[i] = g.claimDrawReasons[i].String()
 	}
-
-	for sq, p := range g.pieces[colorWhite] {
-		o.WhitePieces[sq.toAlgebraic()] = p.pieceType.String()
+	o.GameOverReason = g.gameOverReason.String()
+	o.CanWhiteWinOnTime = g.canWhiteWinOnTime
+	o.CanBlackWinOnTime = g.canBlackWinOnTime
+	o.Result = g.result()
+	o.DrawOfferedBy = ""
+	if g.isDrawOffered {
+		o.DrawOfferedBy = g.drawOfferedBy.String()
 	}
-
-	for i := range g.inCheckBy {
-		o.InCheckBy[i] = g.inCheckBy[i].xy.toAlgebraic()
+	o.PositionHash = fmt.Sprintf("%016x", g.positionHash)
+	o.PositionHistory = make([]string, len(g.positionHistory))
+	for i, key := range g.positionHistory {
+		o.PositionHistory[i] = fmt.Sprintf("%016x", key)
 	}
 
 	return o
 }
 
+func mapPiecesToSortedOutputPieces(pieces map[xy]piece) []OutputPiece {
+	ops := make([]OutputPiece, 0, len(pieces))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if p, ok := pieces[xy{x, y}]; ok {
+				ops = append(ops, OutputPiece{Square: p.xy.toAlgebraic(), PieceType: p.pieceType.String()})
+			}
+		}
+	}
+	return ops
+}
+
 func mapInternalBoardToBoard(b board) Board {
 	return Board{
 		Board:                   b.board,
@@ -251,7 +608,14 @@ func mapBoardToInternalBoard(b Board) board {
 	}
 }
 
-func mapInternalActionToAction(a action) OutputAction {
+// mapInternalActionToAction maps an action that follows from prevGame. The check or checkmate suffix of the action
+// (i.e. `+`, `#` or an empty string) depends on the resulting game, so it must be supplied.
+func mapInternalActionToAction(prevGame game, a action, threatenSuffix string) OutputAction {
+	var san, lan string
+	if a.isMove() {
+		san = algebraicMove(prevGame, a, characteristics{}) + threatenSuffix
+		lan = longAlgebraicMove(a) + threatenSuffix
+	}
 	return OutputAction{
 		FromPieceOwner:     a.fromPiece.owner.String(),
 		FromPieceType:      a.fromPiece.pieceType.String(),
@@ -259,6 +623,12 @@ func mapInternalActionToAction(a action) OutputAction {
 		ToSquare:           a.toXY.toAlgebraic(),
 		IsCapture:          a.isCapture,
 		IsResign:           a.isResign,
+		IsClaimDraw:        a.isClaimDraw,
+		IsTimeout:          a.isTimeout,
+		IsOfferDraw:        a.isOfferDraw,
+		IsAcceptDraw:       a.isAcceptDraw,
+		IsDeclineDraw:      a.isDeclineDraw,
+		IsAbort:            a.isAbort,
 		IsPromotion:        a.isPromotion,
 		IsEnPassant:        a.isEnPassant,
 		IsEnPassantCapture: a.isEnPassantCapture,
@@ -267,17 +637,147 @@ func mapInternalActionToAction(a action) OutputAction {
 		IsQueensideCastle:  a.isQueensideCastle,
 		PromotionPieceType: a.promotionPieceType.String(),
 		CapturedPieceType:  a.capturedPiece.pieceType.String(),
+		UCI:                uciOf(a),
+		SAN:                san,
+		LAN:                lan,
+	}
+}
+
+// uciOf returns the action in UCI notation, or an empty string if it doesn't move any piece.
+func uciOf(a action) string {
+	if !a.isMove() {
+		return ""
 	}
+	return emitUCI(game{}, gameStep{a: a})
 }
 
-func mapGameStepsToOutputGameSteps(gss []gameStep) []OutputGameStep {
+// mapGameStepsToOutputGameSteps maps the games of the steps with the options for steps, except for the last one. The
+// steps must follow from the given initial game.
+func mapGameStepsToOutputGameSteps(initialGame game, gss []gameStep, opts outputOptions) []OutputGameStep {
 	ogs := make([]OutputGameStep, len(gss))
+	prevGame := initialGame
 	for i, gs := range gss {
+		gameOpts := opts.forSteps()
+		if i == len(gss)-1 {
+			gameOpts = opts
+		}
 		ogs[i] = OutputGameStep{
-			Game:         mapGameToOutputGame(gs.g),
-			Action:       mapInternalActionToAction(gs.a),
+			Game:         mapGameToOutputGame(gs.g, gameOpts),
+			Action:       mapInternalActionToAction(prevGame, gs.a, threatenSuffix(gs.g)),
 			ActionString: gs.s,
 		}
+		prevGame = gs.g
 	}
 	return ogs
 }
+
+// mapPGNStepsToOutputGameSteps maps the games of the steps with the options for steps, except for the last one, so
+// variations must be supplied the options for steps. The steps must follow from the given initial game.
+func mapPGNStepsToOutputGameSteps(initialGame game, pss []pgnStep, opts outputOptions) []OutputGameStep {
+	ogs := make([]OutputGameStep, len(pss))
+	prevGame := initialGame
+	for i, ps := range pss {
+		gameOpts := opts.forSteps()
+		if i == len(pss)-1 {
+			gameOpts = opts
+		}
+		ogs[i] = OutputGameStep{
+			Game:         mapGameToOutputGame(ps.g, gameOpts),
+			Action:       mapInternalActionToAction(prevGame, ps.a, threatenSuffix(ps.g)),
+			ActionString: ps.s,
+			Comments:     ps.comments,
+			NAGs:         ps.nags,
+		}
+		// A variation is an alternative to the step's action, so it follows from the same game
+		for _, variation := range ps.variations {
+			ogs[i].Variations = append(ogs[i].Variations, mapPGNStepsToOutputGameSteps(prevGame, variation, opts.forSteps()))
+		}
+		prevGame = ps.g
+	}
+	return ogs
+}
+
+func mapPGNGameToOutputPGN(pg pgnGame, opts outputOptions) OutputPGN {
+	o := OutputPGN{
+		Tags:            make([]PGNTag, len(pg.tags)),
+		Game:            mapGameToOutputGame(pg.initialGame, opts),
+		OutputGameSteps: mapPGNStepsToOutputGameSteps(pg.initialGame, pg.steps, opts),
+		Comments:        pg.comments,
+		Result:          pg.result,
+	}
+	for i, tag := range pg.tags {
+		o.Tags[i] = PGNTag{Name: tag[0], Value: tag[1]}
+	}
+	return o
+}
+
+func mapCharacteristicsToNotationCharacteristics(ch characteristics) NotationCharacteristics {
+	return NotationCharacteristics{
+		CheckSymbol:                ch.usesCheckSymbol,
+		CheckmateSymbol:            ch.usesCheckmateSymbol,
+		CastlingSymbol:             ch.usesCastlingSymbol,
+		PromotionSymbol:            ch.usesPromotionSymbol,
+		EndGameSymbol:              ch.usesEndGameSymbol,
+		FullMoveDot:                ch.usesFullMoveDot,
+		NewlineAsFullMoveSeparator: ch.usesNewlineAsFullMoveSeparator,
+		PieceLetters:               ch.usesPieceLetters,
+	}
+}
+
+func mapNotationCharacteristicsToCharacteristics(nc NotationCharacteristics) (characteristics, error) {
+	if nc.CastlingSymbol != nil && *nc.CastlingSymbol != "O" && *nc.CastlingSymbol != "0" {
+		return characteristics{}, fmt.Errorf("%w: castlingSymbol must be one of {O|0}", errInvalidNotationCharacteristics)
+	}
+	if nc.CheckSymbol != nil && !isOneOf(*nc.CheckSymbol, checkSymbols) {
+		return characteristics{}, fmt.Errorf("%w: checkSymbol must be one of {%v}", errInvalidNotationCharacteristics, strings.Join(checkSymbols, "|"))
+	}
+	if nc.CheckmateSymbol != nil && !isOneOf(*nc.CheckmateSymbol, checkmateSymbols) {
+		return characteristics{}, fmt.Errorf("%w: checkmateSymbol must be one of {%v}", errInvalidNotationCharacteristics, strings.Join(checkmateSymbols, "|"))
+	}
+	if nc.PromotionSymbol != nil && !isOneOf(*nc.PromotionSymbol, promotionSymbols) {
+		return characteristics{}, fmt.Errorf("%w: promotionSymbol must be one of {=|(} or empty", errInvalidNotationCharacteristics)
+	}
+	for _, language := range nc.PieceLetters {
+		if _, ok := algebraicPieceLetters[language]; !ok {
+			return characteristics{}, fmt.Errorf("%w: pieceLetters must be any of %v", errInvalidNotationCharacteristics, pieceLetterLanguages)
+		}
+	}
+	return characteristics{
+		usesCheckSymbol:                nc.CheckSymbol,
+		usesCheckmateSymbol:            nc.CheckmateSymbol,
+		usesCastlingSymbol:             nc.CastlingSymbol,
+		usesPromotionSymbol:            nc.PromotionSymbol,
+		usesEndGameSymbol:              nc.EndGameSymbol,
+		usesFullMoveDot:                nc.FullMoveDot,
+		usesNewlineAsFullMoveSeparator: nc.NewlineAsFullMoveSeparator,
+		usesPieceLetters:               nc.PieceLetters,
+	}, nil
+}
+
+func mapPerftToOutputPerft(g game, counts perftCounts, divide []perftDivide) OutputPerft {
+	o := OutputPerft{PerftCounts: mapPerftCountsToOutputPerftCounts(counts), Divide: make([]OutputPerftDivide, len(divide))}
+	suffixes := map[action]string{}
+	for i, suffix := range g.threatenSuffixes() {
+		suffixes[g.actions[i]] = suffix
+	}
+	for i, d := range divide {
+		o.Divide[i] = OutputPerftDivide{
+			Action:       mapInternalActionToAction(g, d.a, suffixes[d.a]),
+			ActionString: emitUCI(g, gameStep{a: d.a}),
+			PerftCounts:  mapPerftCountsToOutputPerftCounts(d.counts),
+		}
+	}
+	return o
+}
+
+func mapPerftCountsToOutputPerftCounts(c perftCounts) PerftCounts {
+	return PerftCounts{
+		Nodes:      c.nodes,
+		Captures:   c.captures,
+		EnPassants: c.enPassants,
+		Castles:    c.castles,
+		Promotions: c.promotions,
+		Checks:     c.checks,
+		Checkmates: c.checkmates,
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,6 +32,7 @@ func TestAPIDefaultGame(t *testing.T) {
 			Turn:                    "White",
 		},
 		Actions:                 []OutputAction{},
+		NonMoveActions:          []OutputAction{},
 		CanWhiteCastle:          true,
 		CanWhiteKingsideCastle:  true,
 		CanWhiteQueensideCastle: true,
@@ -78,18 +80,26 @@ func TestAPIDefaultGame(t *testing.T) {
 			"h1": "Rook",
 			"h2": "Pawn",
 		},
-		BlackKing:      "e8",
-		WhiteKing:      "e1",
-		IsCheck:        false,
-		IsCheckmate:    false,
-		IsStalemate:    false,
-		IsDraw:         false,
-		IsGameOver:     false,
-		GameOverWinner: "Unknown",
-		InCheckBy:      []string{},
+		BlackKing:         "e8",
+		WhiteKing:         "e1",
+		IsCheck:           false,
+		IsCheckmate:       false,
+		IsStalemate:       false,
+		IsDraw:            false,
+		IsGameOver:        false,
+		GameOverWinner:    "Unknown",
+		InCheckBy:         []string{},
+		RepetitionCount:   1,
+		ClaimDrawReasons:  []string{},
+		CanWhiteWinOnTime: true,
+		CanBlackWinOnTime: true,
+		Result:            "*",
+		PositionHash:      "463b96181691fc9c",
+		PositionHistory:   []string{},
 	}
 	actual := New().DefaultGame()
 	actual.Actions = []OutputAction{} // Not testing every single action on this test
+	actual.NonMoveActions = []OutputAction{}
 	assert.Equal(t, expected, actual)
 }
 
@@ -251,6 +261,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:   "Pawn",
 				FromPieceSquare: "e2",
 				ToSquare:        "e4",
+				UCI:             "e2e4",
+				SAN:             "e4",
+				LAN:             "e2-e4",
 				IsEnPassant:     true,
 			},
 		},
@@ -273,6 +286,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:   "Pawn",
 				FromPieceSquare: "e2",
 				ToSquare:        "e4",
+				UCI:             "e2e4",
+				SAN:             "e4",
+				LAN:             "e2-e4",
 				IsEnPassant:     true,
 			},
 		},
@@ -295,6 +311,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:     "King",
 				FromPieceSquare:   "e8",
 				ToSquare:          "c8",
+				UCI:               "e8c8",
+				SAN:               "O-O-O",
+				LAN:               "O-O-O",
 				IsCastle:          true,
 				IsQueensideCastle: true,
 			},
@@ -318,6 +337,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:    "King",
 				FromPieceSquare:  "e8",
 				ToSquare:         "g8",
+				UCI:              "e8g8",
+				SAN:              "O-O",
+				LAN:              "O-O",
 				IsCastle:         true,
 				IsKingsideCastle: true,
 			},
@@ -341,6 +363,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:     "King",
 				FromPieceSquare:   "e1",
 				ToSquare:          "c1",
+				UCI:               "e1c1",
+				SAN:               "O-O-O",
+				LAN:               "O-O-O",
 				IsCastle:          true,
 				IsQueensideCastle: true,
 			},
@@ -364,6 +389,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:    "King",
 				FromPieceSquare:  "e1",
 				ToSquare:         "g1",
+				UCI:              "e1g1",
+				SAN:              "O-O",
+				LAN:              "O-O",
 				IsCastle:         true,
 				IsKingsideCastle: true,
 			},
@@ -387,6 +415,9 @@ func TestDoAction(t *testing.T) {
 				FromPieceType:     "Bishop",
 				FromPieceSquare:   "b5",
 				ToSquare:          "d7",
+				UCI:               "b5d7",
+				SAN:               "Bxd7+",
+				LAN:               "Bb5xd7+",
 				IsCapture:         true,
 				CapturedPieceType: "Bishop",
 			},
@@ -404,3 +435,1026 @@ func TestDoAction(t *testing.T) {
 		})
 	}
 }
+
+func TestDoActionUCI(t *testing.T) {
+	testCases := []struct {
+		name        string
+		fenString   string
+		uci         string
+		expectedFEN string
+		expectedUCI string
+		err         error
+	}{
+		{
+			name:        "pawn moves",
+			fenString:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			uci:         "e2e4",
+			expectedFEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			expectedUCI: "e2e4",
+		},
+		{
+			name:        "upper case is accepted",
+			fenString:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			uci:         "G1F3",
+			expectedFEN: "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+			expectedUCI: "g1f3",
+		},
+		{
+			name:        "white kingside castles with king to g1",
+			fenString:   "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			uci:         "e1g1",
+			expectedFEN: "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+			expectedUCI: "e1g1",
+		},
+		{
+			name:        "white kingside castles with king takes rook",
+			fenString:   "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			uci:         "e1h1",
+			expectedFEN: "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1",
+			expectedUCI: "e1g1",
+		},
+		{
+			name:        "black queenside castles with king takes rook",
+			fenString:   "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1",
+			uci:         "e8a8",
+			expectedFEN: "2kr3r/8/8/8/8/8/8/R3K2R w KQ - 1 2",
+			expectedUCI: "e8c8",
+		},
+		{
+			name:      "king can't take own rook without castling rights",
+			fenString: "r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1",
+			uci:       "e1h1",
+			err:       errInvalidActionForGivenGame,
+		},
+		{
+			name:        "pawn promotes",
+			fenString:   "8/4P3/8/8/8/8/8/k3K3 w - - 0 1",
+			uci:         "e7e8n",
+			expectedFEN: "4N3/8/8/8/8/8/8/k3K3 b - - 0 1",
+			expectedUCI: "e7e8n",
+		},
+		{
+			name:      "promotion requires the piece type",
+			fenString: "8/4P3/8/8/8/8/8/k3K3 w - - 0 1",
+			uci:       "e7e8",
+			err:       errInvalidActionForGivenGame,
+		},
+		{
+			name:      "errInvalidUCI: too short",
+			fenString: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			uci:       "e2e",
+			err:       errInvalidUCI,
+		},
+		{
+			name:      "errInvalidUCI: invalid promotion piece",
+			fenString: "8/4P3/8/8/8/8/8/k3K3 w - - 0 1",
+			uci:       "e7e8k",
+			err:       errInvalidUCI,
+		},
+		{
+			name:      "errAlgebraicSquareInvalidOrOutOfBounds",
+			fenString: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			uci:       "e2e9",
+			err:       errAlgebraicSquareInvalidOrOutOfBounds,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The squares are ignored when the UCI move is supplied
+			inputAction := InputAction{FromSquare: "a1", ToSquare: "a2", UCI: tc.uci}
+			actualOutputGame, actualOutputAction, err := New().DoAction(InputGame{FENString: tc.fenString}, inputAction)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedFEN, actualOutputGame.FENString)
+			assert.Equal(t, tc.expectedUCI, actualOutputAction.UCI)
+		})
+	}
+}
+
+func TestDoActionSAN(t *testing.T) {
+	testCases := []struct {
+		name        string
+		fenString   string
+		san         string
+		expectedFEN string
+		err         error
+		errMessage  string
+	}{
+		{
+			name:        "knight moves",
+			fenString:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:         "Nf3",
+			expectedFEN: "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+		},
+		{
+			name:        "disambiguated knight moves",
+			fenString:   "rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 2",
+			san:         "Nbd7",
+			expectedFEN: "r1bqkb1r/pppnpppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR w KQkq - 1 3",
+		},
+		{
+			name:        "pawn captures en passant",
+			fenString:   "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+			san:         "exd6 e.p.",
+			expectedFEN: "4k3/8/3P4/8/8/8/8/4K3 b - - 0 1",
+		},
+		{
+			name:        "queenside castles with check",
+			fenString:   "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			san:         "O-O-O+",
+			expectedFEN: "3k4/8/8/8/8/8/8/2KR4 b - - 1 1",
+		},
+		{
+			name:        "pawn promotes with checkmate",
+			fenString:   "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			san:         "e8=Q#",
+			expectedFEN: "k3Q3/8/1K6/8/8/8/8/8 b - - 0 1",
+		},
+		{
+			name:       "errAmbiguousSAN",
+			fenString:  "rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 2",
+			san:        "Nd7",
+			err:        errAmbiguousSAN,
+			errMessage: "ambiguous san: Nd7 could be any of [Nbd7 Nfd7]",
+		},
+		{
+			name:       "errIllegalSAN: candidates to the same square",
+			fenString:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:        "e4+",
+			err:        errIllegalSAN,
+			errMessage: "illegal san: e4+ is not a valid action for the game; candidates are [e4]",
+		},
+		{
+			name:       "errIllegalSAN: candidates of the same piece type",
+			fenString:  "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:        "Nd4",
+			err:        errIllegalSAN,
+			errMessage: "illegal san: Nd4 is not a valid action for the game; candidates are [Na3 Nc3 Nf3 Nh3]",
+		},
+		{
+			name:      "errInvalidSAN",
+			fenString: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			san:       "Zz9",
+			err:       errInvalidSAN,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutputGame, _, err := New().DoAction(InputGame{FENString: tc.fenString}, InputAction{SAN: tc.san})
+			require.True(t, errors.Is(err, tc.err), "expected %v but got %v", tc.err, err)
+			if err != nil {
+				if tc.errMessage != "" {
+					assert.Equal(t, tc.errMessage, err.Error())
+				}
+				return
+			}
+			assert.Equal(t, tc.expectedFEN, actualOutputGame.FENString)
+		})
+	}
+}
+
+func TestDoActionEndsGame(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		inputGame                InputGame
+		inputAction              InputAction
+		expectedIsDraw           bool
+		expectedGameOverWinner   string
+		expectedGameOverReason   string
+		expectedClaimDrawReasons []string
+		expectedResult           string
+		err                      error
+	}{
+		{
+			name:                   "resigns",
+			inputGame:              InputGame{},
+			inputAction:            InputAction{IsResign: true},
+			expectedGameOverWinner: "Black",
+			expectedGameOverReason: "resignation",
+			expectedResult:         "0-1",
+		},
+		{
+			name:                   "runs out of time",
+			inputGame:              InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 b - - 0 1"},
+			inputAction:            InputAction{IsTimeout: true},
+			expectedGameOverWinner: "White",
+			expectedGameOverReason: "timeout",
+			expectedResult:         "1-0",
+		},
+		{
+			name:                   "runs out of time, but the opponent can't win",
+			inputGame:              InputGame{FENString: "4k3/8/8/8/8/8/8/R1N1K3 w - - 0 1"},
+			inputAction:            InputAction{IsTimeout: true},
+			expectedIsDraw:         true,
+			expectedGameOverWinner: "Unknown",
+			expectedGameOverReason: "timeout",
+			expectedResult:         "1/2-1/2",
+		},
+		{
+			name:                   "claims a draw after 50 moves",
+			inputGame:              InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 w - - 100 80"},
+			inputAction:            InputAction{IsClaimDraw: true},
+			expectedIsDraw:         true,
+			expectedGameOverWinner: "Unknown",
+			expectedGameOverReason: "fiftyMove",
+			expectedResult:         "1/2-1/2",
+		},
+		{
+			name:        "can't claim a draw before 50 moves",
+			inputGame:   InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 w - - 99 80"},
+			inputAction: InputAction{IsClaimDraw: true},
+			err:         errInvalidActionForGivenGame,
+		},
+		{
+			name:                     "can claim a draw after 50 moves, but doesn't",
+			inputGame:                InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 w - - 100 80"},
+			inputAction:              InputAction{FromSquare: "a1", ToSquare: "a2"},
+			expectedGameOverWinner:   "Unknown",
+			expectedClaimDrawReasons: []string{"fiftyMove"},
+			expectedResult:           "*",
+		},
+		{
+			name:                   "draws automatically after 75 moves",
+			inputGame:              InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 w - - 149 80"},
+			inputAction:            InputAction{FromSquare: "a1", ToSquare: "a2"},
+			expectedIsDraw:         true,
+			expectedGameOverWinner: "Unknown",
+			expectedGameOverReason: "seventyFiveMove",
+			expectedResult:         "1/2-1/2",
+		},
+		{
+			name:                   "checkmate takes precedence over the 75-move rule",
+			inputGame:              InputGame{FENString: "4k3/R7/8/8/8/8/8/1R2K3 w - - 149 80"},
+			inputAction:            InputAction{FromSquare: "b1", ToSquare: "b8"},
+			expectedGameOverWinner: "White",
+			expectedGameOverReason: "checkmate",
+			expectedResult:         "1-0",
+		},
+		{
+			name:                   "stalemate is a draw",
+			inputGame:              InputGame{FENString: "k7/8/1K6/8/8/8/2Q5/8 w - - 0 1"},
+			inputAction:            InputAction{FromSquare: "c2", ToSquare: "c7"},
+			expectedGameOverWinner: "Unknown",
+			expectedGameOverReason: "stalemate",
+			expectedResult:         "1/2-1/2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutputGame, _, err := New().DoAction(tc.inputGame, tc.inputAction)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedIsDraw, actualOutputGame.IsDraw)
+			assert.Equal(t, tc.expectedGameOverReason != "", actualOutputGame.IsGameOver)
+			assert.Equal(t, tc.expectedGameOverWinner, actualOutputGame.GameOverWinner)
+			assert.Equal(t, tc.expectedGameOverReason, actualOutputGame.GameOverReason)
+			assert.Equal(t, len(tc.expectedClaimDrawReasons) > 0, actualOutputGame.CanClaimDraw)
+			assert.ElementsMatch(t, tc.expectedClaimDrawReasons, actualOutputGame.ClaimDrawReasons)
+			assert.Equal(t, tc.expectedResult, actualOutputGame.Result)
+			if tc.expectedIsDraw {
+				assert.Empty(t, actualOutputGame.Actions)
+			}
+		})
+	}
+}
+
+func TestDoActionDrawOffersAndAbort(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		inputGame              InputGame
+		inputActions           []InputAction
+		expectedDrawOfferedBy  string
+		expectedGameOverReason string
+		expectedResult         string
+		expectedTurn           string
+		err                    error
+	}{
+		{
+			name:      "offers a draw, moves, and the opponent accepts",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{FromSquare: "e2", ToSquare: "e4"},
+				{IsAcceptDraw: true},
+			},
+			expectedDrawOfferedBy:  "",
+			expectedGameOverReason: "agreement",
+			expectedResult:         "1/2-1/2",
+			expectedTurn:           "Black",
+		},
+		{
+			name:      "offers a draw and moves, so it's pending",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{FromSquare: "e2", ToSquare: "e4"},
+			},
+			expectedDrawOfferedBy: "White",
+			expectedResult:        "*",
+			expectedTurn:          "Black",
+		},
+		{
+			name:      "can't accept one's own draw offer",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{IsAcceptDraw: true},
+			},
+			err: errInvalidActionForGivenGame,
+		},
+		{
+			name:      "can't offer a draw twice",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{IsOfferDraw: true},
+			},
+			err: errInvalidActionForGivenGame,
+		},
+		{
+			name:      "declines a draw",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{FromSquare: "e2", ToSquare: "e4"},
+				{IsDeclineDraw: true},
+			},
+			expectedDrawOfferedBy: "",
+			expectedResult:        "*",
+			expectedTurn:          "Black",
+		},
+		{
+			name:      "a draw offer lapses if the opponent moves",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{IsOfferDraw: true},
+				{FromSquare: "e2", ToSquare: "e4"},
+				{FromSquare: "e7", ToSquare: "e5"},
+			},
+			expectedDrawOfferedBy: "",
+			expectedResult:        "*",
+			expectedTurn:          "White",
+		},
+		{
+			name:                   "accepts a pending draw offer supplied with the game",
+			inputGame:              InputGame{FENString: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", DrawOfferedBy: "White"},
+			inputActions:           []InputAction{{IsAcceptDraw: true}},
+			expectedGameOverReason: "agreement",
+			expectedResult:         "1/2-1/2",
+			expectedTurn:           "Black",
+		},
+		{
+			name:         "invalid pending draw offer",
+			inputGame:    InputGame{DrawOfferedBy: "Nobody"},
+			inputActions: []InputAction{{IsAcceptDraw: true}},
+			err:          errInvalidDrawOfferedBy,
+		},
+		{
+			name:      "aborts before the first move",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{FromSquare: "e2", ToSquare: "e4"},
+				{IsAbort: true},
+			},
+			expectedGameOverReason: "aborted",
+			expectedResult:         "*",
+			expectedTurn:           "Black",
+		},
+		{
+			name:      "can't abort after both players moved",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{FromSquare: "e2", ToSquare: "e4"},
+				{FromSquare: "e7", ToSquare: "e5"},
+				{IsAbort: true},
+			},
+			err: errInvalidActionForGivenGame,
+		},
+		{
+			name:      "aborts after White's first move",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{FromSquare: "g1", ToSquare: "f3"},
+				{IsAbort: true},
+			},
+			expectedGameOverReason: "aborted",
+			expectedResult:         "*",
+			expectedTurn:           "Black",
+		},
+		{
+			name:         "can't abort a game set up on its first move",
+			inputGame:    InputGame{FENString: "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"},
+			inputActions: []InputAction{{IsAbort: true}},
+			err:          errInvalidActionForGivenGame,
+		},
+		{
+			name:      "can't abort after returning to the initial position",
+			inputGame: InputGame{},
+			inputActions: []InputAction{
+				{FromSquare: "g1", ToSquare: "f3"},
+				{FromSquare: "g8", ToSquare: "f6"},
+				{FromSquare: "f3", ToSquare: "g1"},
+				{FromSquare: "f6", ToSquare: "g8"},
+				{IsAbort: true},
+			},
+			err: errInvalidActionForGivenGame,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				outputGame OutputGame
+				err        error
+				inputGame  = tc.inputGame
+			)
+			for _, inputAction := range tc.inputActions {
+				outputGame, _, err = New().DoAction(inputGame, inputAction)
+				if err != nil {
+					break
+				}
+				inputGame = InputGame{FENString: outputGame.FENString, DrawOfferedBy: outputGame.DrawOfferedBy, PositionHistory: outputGame.PositionHistory}
+			}
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedDrawOfferedBy, outputGame.DrawOfferedBy)
+			assert.Equal(t, tc.expectedGameOverReason != "", outputGame.IsGameOver)
+			assert.Equal(t, tc.expectedGameOverReason, outputGame.GameOverReason)
+			assert.Equal(t, tc.expectedResult, outputGame.Result)
+			assert.Equal(t, tc.expectedTurn, outputGame.Board.Turn)
+		})
+	}
+}
+
+func TestDoActionRepetitionAcrossCalls(t *testing.T) {
+	var (
+		a          = New()
+		inputGame  = InputGame{}
+		outputGame OutputGame
+		err        error
+	)
+	for _, san := range []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"} {
+		outputGame, _, err = a.DoAction(inputGame, InputAction{SAN: san})
+		require.NoError(t, err)
+		inputGame = InputGame{FENString: outputGame.FENString, PositionHistory: outputGame.PositionHistory}
+	}
+	assert.Equal(t, 3, outputGame.RepetitionCount)
+	assert.Len(t, outputGame.PositionHistory, 8)
+	require.True(t, outputGame.CanClaimDraw)
+
+	outputGame, _, err = a.DoAction(inputGame, InputAction{IsClaimDraw: true})
+	require.NoError(t, err)
+	assert.Equal(t, "repetition", outputGame.GameOverReason)
+
+	// Without the history, the repetitions are unknown
+	outputGame, err = a.ParseGame(InputGame{FENString: inputGame.FENString})
+	require.NoError(t, err)
+	assert.Equal(t, 1, outputGame.RepetitionCount)
+
+	_, err = a.ParseGame(InputGame{PositionHistory: []string{"not a key"}})
+	assert.Equal(t, errInvalidPositionHistory, err)
+}
+
+func TestParseNotation(t *testing.T) {
+	var (
+		scholarsMateFEN = "r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4"
+		hash            = "#"
+		mate            = "mate"
+		plus            = "+"
+		letterO         = "O"
+		invalidSymbol   = "@"
+		dot             = true
+		noDot           = false
+		newline         = true
+		noNewline       = false
+	)
+	testCases := []struct {
+		name                    string
+		notationString          string
+		notation                string
+		characteristics         NotationCharacteristics
+		expectedFEN             string
+		expectedNotation        string
+		expectedCharacteristics NotationCharacteristics
+		expectedErrPly          int
+		err                     error
+	}{
+		{
+			name:                    "parses Algebraic Notation",
+			notationString:          "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#",
+			notation:                "algebraic",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "algebraic",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &hash, FullMoveDot: &dot, PieceLetters: []string{"en"}, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "defaults to Algebraic Notation",
+			notationString:          "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "algebraic",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &hash, FullMoveDot: &dot, PieceLetters: []string{"en"}, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "parses Long Algebraic Notation",
+			notationString:          "1. e2-e4 e7-e5 2. Bf1-c4 Nb8-c6 3. Qd1-h5 Ng8-f6 4. Qh5xf7#",
+			notation:                "lan",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "lan",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &hash, FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "parses UCI",
+			notationString:          "e2e4 e7e5 f1c4 b8c6 d1h5 g8f6 h5f7",
+			notation:                "uci",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "uci",
+			expectedCharacteristics: NotationCharacteristics{NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "parses Descriptive Notation",
+			notationString:          "1. P-K4 P-K4 2. B-B4 N-QB3 3. Q-R5 N-B3 4. QxBP mate",
+			notation:                "descriptive",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "descriptive",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &mate, FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "parses ICCF Numeric Notation",
+			notationString:          "1. 5254 5755 2. 6134 2836 3. 4185 7866 4. 8567",
+			notation:                "iccf",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "iccf",
+			expectedCharacteristics: NotationCharacteristics{FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects Algebraic Notation in German",
+			notationString:          "1. e4 e5 2. Lc4 Sc6 3. Dh5 Sf6 4. Dxf7#",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "algebraic",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &hash, FullMoveDot: &dot, PieceLetters: []string{"de"}, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects Long Algebraic Notation",
+			notationString:          "1. e2-e4 e7-e5 2. Bf1-c4 Nb8-c6 3. Qd1-h5 Ng8-f6 4. Qh5xf7#",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "lan",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &hash, FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects UCI",
+			notationString:          "e2e4 e7e5 f1c4 b8c6 d1h5 g8f6 h5f7",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "uci",
+			expectedCharacteristics: NotationCharacteristics{NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects Descriptive Notation",
+			notationString:          "1. P-K4 P-K4 2. B-B4 N-QB3 3. Q-R5 N-B3 4. QxBP mate",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "descriptive",
+			expectedCharacteristics: NotationCharacteristics{CheckmateSymbol: &mate, FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects ICCF Numeric Notation",
+			notationString:          "1. 5254 5755 2. 6134 2836 3. 4185 7866 4. 8567",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "iccf",
+			expectedCharacteristics: NotationCharacteristics{FullMoveDot: &dot, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:                    "detects ICCF Numeric Notation without full move numbers",
+			notationString:          "5254 5755 6134 2836 4185 7866 8567",
+			notation:                "auto",
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "iccf",
+			expectedCharacteristics: NotationCharacteristics{NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:             "detects a single ICCF Numeric Notation action, rather than a full move number",
+			notationString:   "7163",
+			notation:         "auto",
+			expectedFEN:      "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+			expectedNotation: "iccf",
+		},
+		{
+			name:             "detects a single UCI action",
+			notationString:   "g1f3",
+			notation:         "auto",
+			expectedFEN:      "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+			expectedNotation: "uci",
+		},
+		{
+			name:             "no notation reads a8a8 as an action that doesn't move pieces",
+			notationString:   "e2e4 a8a8 e7e5",
+			notation:         "auto",
+			expectedNotation: "algebraic",
+			err:              errors.New("at line 1, column 6 (offset 5), ply 2 [a8a8]: doesn't match any legal action"),
+		},
+		{
+			name:             "a full move number alone doesn't parse in any notation",
+			notationString:   "12",
+			notation:         "auto",
+			expectedNotation: "algebraic",
+			err:              errors.New("at line 1, column 1 (offset 0), ply 1 [12]: didn't match any action"),
+		},
+		{
+			name:             "when no notation parses the match, the one that parsed the most actions is blamed",
+			notationString:   "1. e4 e5 2. Bc4 Nc6 3. Qxf7#",
+			notation:         "auto",
+			expectedNotation: "algebraic",
+			err:              errors.New("at line 1, column 24 (offset 23), ply 5 [Qxf7#]: doesn't match any legal action"),
+		},
+		{
+			name:                    "returns the enforced characteristics together with the learnt ones",
+			notationString:          "1. e4 e5 2. Lc4 Sc6 3. Dh5 Sf6 4. Dxf7#",
+			characteristics:         NotationCharacteristics{CheckSymbol: &plus, PieceLetters: []string{"de"}},
+			expectedFEN:             scholarsMateFEN,
+			expectedNotation:        "algebraic",
+			expectedCharacteristics: NotationCharacteristics{CheckSymbol: &plus, CheckmateSymbol: &hash, FullMoveDot: &dot, PieceLetters: []string{"de"}, NewlineAsFullMoveSeparator: &noNewline},
+		},
+		{
+			name:             "enforces the castling symbol",
+			notationString:   "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. 0-0",
+			characteristics:  NotationCharacteristics{CastlingSymbol: &letterO},
+			expectedNotation: "algebraic",
+			err:              errors.New("expecting CastlingSymbol O but found 0"),
+		},
+		{
+			name:             "enforces the checkmate symbol on checkmates without a symbol",
+			notationString:   "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7",
+			characteristics:  NotationCharacteristics{CheckmateSymbol: &hash},
+			expectedNotation: "algebraic",
+			expectedErrPly:   7,
+			err:              errors.New("expecting CheckmateSymbol # but found none"),
+		},
+		{
+			name:             "enforces the check symbol on checks without a symbol",
+			notationString:   "1. e4 f5 2. Qh5",
+			characteristics:  NotationCharacteristics{CheckSymbol: &plus},
+			expectedNotation: "algebraic",
+			expectedErrPly:   3,
+			err:              errors.New("expecting CheckSymbol + but found none"),
+		},
+		{
+			name:             "enforces the piece letters",
+			notationString:   "1. e4 e5 2. Nf3",
+			characteristics:  NotationCharacteristics{PieceLetters: []string{"de"}},
+			expectedNotation: "algebraic",
+			err:              errors.New("expecting PieceLetters [de] but found [en]"),
+		},
+		{
+			name:             "enforces the full move dot",
+			notationString:   "1. e4 e5 2 Nf3",
+			characteristics:  NotationCharacteristics{FullMoveDot: &dot},
+			expectedNotation: "algebraic",
+			err:              errors.New("at line 1, column 10 (offset 9), ply 3 [2]: expecting FullMoveDot true but found false"),
+		},
+		{
+			name:             "enforces the lack of a full move dot",
+			notationString:   "1. e4 e5 2. Nf3",
+			characteristics:  NotationCharacteristics{FullMoveDot: &noDot},
+			expectedNotation: "algebraic",
+			err:              errors.New("at line 1, column 1 (offset 0), ply 1 [1.]: expecting FullMoveDot false but found true"),
+		},
+		{
+			name:             "enforces newlines as full move separators",
+			notationString:   "1. e4 e5 2. Nf3",
+			characteristics:  NotationCharacteristics{NewlineAsFullMoveSeparator: &newline},
+			expectedNotation: "algebraic",
+			err:              errors.New("expecting NewlineAsFullMoveSeparator true but found false"),
+		},
+		{
+			name:             "enforces spaces as full move separators",
+			notationString:   "1. e4 e5\n2. Nf3",
+			characteristics:  NotationCharacteristics{NewlineAsFullMoveSeparator: &noNewline},
+			expectedNotation: "algebraic",
+			err:              errors.New("expecting NewlineAsFullMoveSeparator false but found true"),
+		},
+		{
+			name:                    "learns that full moves are on their own lines, without dots",
+			notationString:          "1 e4 e5\n2 Nf3",
+			expectedFEN:             "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+			expectedNotation:        "algebraic",
+			expectedCharacteristics: NotationCharacteristics{FullMoveDot: &noDot, NewlineAsFullMoveSeparator: &newline, PieceLetters: []string{"en"}},
+		},
+		{
+			name:            "errInvalidNotationCharacteristics",
+			notationString:  "1. e4 e5",
+			characteristics: NotationCharacteristics{CastlingSymbol: &invalidSymbol},
+			err:             errInvalidNotationCharacteristics,
+		},
+		{
+			name:            "errInvalidNotationCharacteristics on an invalid check symbol",
+			notationString:  "1. e4 e5",
+			characteristics: NotationCharacteristics{CheckSymbol: &invalidSymbol},
+			err:             errInvalidNotationCharacteristics,
+		},
+		{
+			name:            "errInvalidNotationCharacteristics on an invalid checkmate symbol",
+			notationString:  "1. e4 e5",
+			characteristics: NotationCharacteristics{CheckmateSymbol: &plus},
+			err:             errInvalidNotationCharacteristics,
+		},
+		{
+			name:            "errInvalidNotationCharacteristics on an invalid promotion symbol",
+			notationString:  "1. e4 e5",
+			characteristics: NotationCharacteristics{PromotionSymbol: &invalidSymbol},
+			err:             errInvalidNotationCharacteristics,
+		},
+		{
+			name:           "errUnknownInputNotation",
+			notationString: "1. e4 e5",
+			notation:       "klingon",
+			err:            errUnknownInputNotation,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, steps, outputNotation, err := New().ParseNotation(InputGame{}, tc.notationString, tc.notation, tc.characteristics)
+			if tc.err != nil {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err.Error())
+				assert.Equal(t, tc.expectedNotation, outputNotation.Notation)
+				if tc.expectedErrPly != 0 {
+					var ne NotationError
+					require.True(t, errors.As(err, &ne), err)
+					assert.Equal(t, tc.expectedErrPly, ne.Ply)
+					assert.Len(t, steps, tc.expectedErrPly-1)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFEN, steps[len(steps)-1].Game.FENString)
+			assert.Equal(t, tc.expectedNotation, outputNotation.Notation)
+			assert.Equal(t, tc.expectedCharacteristics, outputNotation.Characteristics)
+		})
+	}
+}
+
+func TestConvertNotation(t *testing.T) {
+	var (
+		mate        = "mate"
+		zero        = "0"
+		parenthesis = "("
+	)
+	testCases := []struct {
+		name                  string
+		inputGame             InputGame
+		notationString        string
+		fromNotation          string
+		toNotation            string
+		toCharacteristics     NotationCharacteristics
+		expectedActionStrings []string
+		err                   error
+	}{
+		{
+			name:                  "converts Scholar's mate to Long Algebraic Notation",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qxf7#",
+			toNotation:            "lan",
+			expectedActionStrings: []string{"e2-e4", "e7-e5", "Bf1-c4", "Nb8-c6", "Qd1-h5", "Ng8-f6", "Qh5xf7#"},
+		},
+		{
+			name:                  "converts Scholar's mate to UCI",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qxf7#",
+			toNotation:            "uci",
+			expectedActionStrings: []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"},
+		},
+		{
+			name:                  "converts Scholar's mate to Descriptive Notation",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qxf7#",
+			toNotation:            "descriptive",
+			expectedActionStrings: []string{"P-K4", "P-K4", "B-B4", "N-QB3", "Q-R5", "N-B3", "QxBP mate"},
+		},
+		{
+			name:                  "normalises Algebraic Notation",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qf7mate",
+			toNotation:            "algebraic",
+			expectedActionStrings: []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"},
+		},
+		{
+			name:                  "reproduces the piece letters and checkmate symbol of a style",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5\n2. Bc4 Nc6\n3. Qh5 Nf6??\n4. Qxf7#",
+			toNotation:            "algebraic",
+			toCharacteristics:     NotationCharacteristics{CheckmateSymbol: &mate, PieceLetters: []string{"de"}},
+			expectedActionStrings: []string{"e4", "e5", "Lc4", "Sc6", "Dh5", "Sf6", "Dxf7mate"},
+		},
+		{
+			name:                  "reproduces the castling symbol of a style",
+			inputGame:             InputGame{},
+			notationString:        "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O",
+			toNotation:            "algebraic",
+			toCharacteristics:     NotationCharacteristics{CastlingSymbol: &zero},
+			expectedActionStrings: []string{"e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "0-0"},
+		},
+		{
+			name:                  "reproduces the promotion symbol of a style",
+			inputGame:             InputGame{FENString: "k7/4P3/1K6/8/8/8/8/8 w - - 0 1"},
+			notationString:        "1. e8=Q#",
+			toNotation:            "algebraic",
+			toCharacteristics:     NotationCharacteristics{PromotionSymbol: &parenthesis},
+			expectedActionStrings: []string{"e8(Q)#"},
+		},
+		{
+			name:                  "converts ICCF Numeric Notation to Algebraic Notation",
+			inputGame:             InputGame{},
+			notationString:        "1. 5254 5755",
+			fromNotation:          "iccf",
+			toNotation:            "algebraic",
+			expectedActionStrings: []string{"e4", "e5"},
+		},
+		{
+			name:                  "converts Descriptive Notation to Algebraic Notation",
+			inputGame:             InputGame{},
+			notationString:        "1. P-K4 P-K4",
+			fromNotation:          "descriptive",
+			toNotation:            "algebraic",
+			expectedActionStrings: []string{"e4", "e5"},
+		},
+		{
+			name:                  "converts from an automatically detected notation",
+			inputGame:             InputGame{},
+			notationString:        "1. 5254 5755 2. 7163",
+			fromNotation:          "auto",
+			toNotation:            "algebraic",
+			expectedActionStrings: []string{"e4", "e5", "Nf3"},
+		},
+		{
+			name:           "errUnknownNotation",
+			inputGame:      InputGame{},
+			notationString: "1. e4 e5",
+			toNotation:     "klingon",
+			err:            errUnknownNotation,
+		},
+		{
+			name:           "errUnknownInputNotation",
+			inputGame:      InputGame{},
+			notationString: "1. e4 e5",
+			fromNotation:   "klingon",
+			toNotation:     "algebraic",
+			err:            errUnknownInputNotation,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, outputGameSteps, err := New().ConvertNotation(tc.inputGame, tc.notationString, tc.fromNotation, tc.toNotation, tc.toCharacteristics)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+			actionStrings := make([]string, len(outputGameSteps))
+			for i := range outputGameSteps {
+				actionStrings[i] = outputGameSteps[i].ActionString
+			}
+			assert.Equal(t, tc.expectedActionStrings, actionStrings)
+		})
+	}
+}
+
+func TestConvertNotationRoundTripsPromotionSymbols(t *testing.T) {
+	for _, notationString := range []string{"1. e8=Q#", "1. e8(Q)#", "1. e8Q#", "1. exf8=Q#", "1. exf8(Q)#", "1. exf8Q#"} {
+		t.Run(notationString, func(t *testing.T) {
+			inputGame := InputGame{FENString: "k4b2/4P3/1K6/8/8/8/8/8 w - - 0 1"}
+			_, _, outputNotation, err := New().ParseNotation(inputGame, notationString, "algebraic", NotationCharacteristics{})
+			require.NoError(t, err)
+			_, outputGameSteps, err := New().ConvertNotation(inputGame, notationString, "algebraic", "algebraic", outputNotation.Characteristics)
+			require.NoError(t, err)
+			require.Len(t, outputGameSteps, 1)
+			assert.Equal(t, notationString[len("1. "):], outputGameSteps[0].ActionString)
+		})
+	}
+}
+
+func TestAPIParsePGN(t *testing.T) {
+	outputPGN, err := New().ParsePGN(`[Event "Casual game"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 {Italian} (2. Nf3 Nc6) 2... Nc6 3. Qh5 Nf6?? 4. Qxf7# 1-0`)
+	require.NoError(t, err)
+	assert.Equal(t, []PGNTag{{Name: "Event", Value: "Casual game"}, {Name: "Result", Value: "1-0"}}, outputPGN.Tags)
+	assert.Equal(t, "1-0", outputPGN.Result)
+	assert.Equal(t, defaultFEN, outputPGN.Game.FENString)
+	require.Len(t, outputPGN.OutputGameSteps, 7)
+	assert.Equal(t, []string{"Italian"}, outputPGN.OutputGameSteps[2].Comments)
+	assert.Equal(t, []int{4}, outputPGN.OutputGameSteps[5].NAGs)
+	require.Len(t, outputPGN.OutputGameSteps[2].Variations, 1)
+	require.Len(t, outputPGN.OutputGameSteps[2].Variations[0], 2)
+	assert.Equal(t, "g1", outputPGN.OutputGameSteps[2].Variations[0][0].Action.FromPieceSquare)
+	assert.True(t, outputPGN.OutputGameSteps[6].Game.IsCheckmate)
+}
+
+func TestExportPGN(t *testing.T) {
+	pgn, err := New().ExportPGN(
+		InputGame{},
+		[]InputAction{
+			{FromSquare: "e2", ToSquare: "e4"},
+			{FromSquare: "e7", ToSquare: "e5"},
+			{FromSquare: "f1", ToSquare: "c4"},
+			{FromSquare: "b8", ToSquare: "c6"},
+			{FromSquare: "d1", ToSquare: "h5"},
+			{FromSquare: "g8", ToSquare: "f6"},
+			{FromSquare: "h5", ToSquare: "f7"},
+		},
+		[]PGNTag{{Name: "White", Value: `Scholar "The Mate"`}, {Name: "Black", Value: "Victim"}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Scholar \"The Mate\""]
+[Black "Victim"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0
+`, pgn)
+
+	_, err = New().ExportPGN(InputGame{}, []InputAction{{FromSquare: "e2", ToSquare: "e5"}}, nil)
+	assert.Equal(t, errInvalidActionForGivenGame, err)
+}
+
+func TestExportPGNSteps(t *testing.T) {
+	pgn := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+1. e4 e5 2. Nf3 {The most common move.} 2... Nc6 (2... d6 $6 3. d4) 3. Bb5 *
+`
+	outputPGN, err := New().ParsePGN(pgn)
+	require.NoError(t, err)
+	actual, err := New().ExportPGNSteps(InputGame{}, outputPGN.OutputGameSteps, outputPGN.Tags)
+	require.NoError(t, err)
+	assert.Equal(t, pgn, actual)
+}
+
+func TestParseGameActionsOrder(t *testing.T) {
+	expected := []string{
+		"b7b8Queen", "b7b8Rook", "b7b8Bishop", "b7b8Knight",
+		"a1a8", "a1a7", "a1a6", "a1a5", "a1a4", "a1a3", "a1a2", "a1b1", "a1c1", "a1d1",
+		"e1d2", "e1e2", "e1f2", "e1c1", "e1d1", "e1f1", "e1g1",
+		"h1h8", "h1h7", "h1h6", "h1h5", "h1h4", "h1h3", "h1h2", "h1f1", "h1g1",
+		"resign", "timeout", "acceptDraw", "declineDraw",
+	}
+	for i := 0; i < 10; i++ {
+		outputGame, err := New().ParseGame(InputGame{FENString: "4k3/1P6/8/8/8/8/8/R3K2R w KQ - 0 1", DrawOfferedBy: "Black"})
+		require.NoError(t, err)
+		actual := []string{}
+		for _, a := range append(outputGame.Actions, outputGame.NonMoveActions...) {
+			switch {
+			case a.IsResign:
+				actual = append(actual, "resign")
+			case a.IsTimeout:
+				actual = append(actual, "timeout")
+			case a.IsAcceptDraw:
+				actual = append(actual, "acceptDraw")
+			case a.IsDeclineDraw:
+				actual = append(actual, "declineDraw")
+			case a.IsAbort:
+				actual = append(actual, "abort")
+			default:
+				actual = append(actual, a.FromPieceSquare+a.ToSquare+a.PromotionPieceType)
+			}
+		}
+		require.Equal(t, expected, actual)
+	}
+}
+
+func TestSortedPieceArrays(t *testing.T) {
+	outputGame, err := New().ParseGame(InputGame{FENString: "4k3/1P6/8/8/8/8/8/R3K2R w KQ - 0 1"})
+	require.NoError(t, err)
+	assert.Nil(t, outputGame.BlackPieceList)
+	assert.Nil(t, outputGame.WhitePieceList)
+
+	outputGame, err = New(WithSortedPieceArrays()).ParseGame(InputGame{FENString: "4k3/1P6/8/8/8/8/8/R3K2R w KQ - 0 1"})
+	require.NoError(t, err)
+	assert.Equal(t, []OutputPiece{{Square: "e8", PieceType: "King"}}, outputGame.BlackPieceList)
+	assert.Equal(t, []OutputPiece{
+		{Square: "b7", PieceType: "Pawn"},
+		{Square: "a1", PieceType: "Rook"},
+		{Square: "e1", PieceType: "King"},
+		{Square: "h1", PieceType: "Rook"},
+	}, outputGame.WhitePieceList)
+
+	_, steps, _, err := New(WithSortedPieceArrays()).ParseNotation(InputGame{}, "1. e4", "algebraic", NotationCharacteristics{})
+	require.NoError(t, err)
+	assert.Len(t, steps[0].Game.WhitePieceList, 16)
+	assert.Equal(t, OutputPiece{Square: "e4", PieceType: "Pawn"}, steps[0].Game.WhitePieceList[0])
+}
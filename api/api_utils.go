@@ -1,30 +1,96 @@
 package api
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
 func (a API) parseGame(g InputGame) (game, error) {
+	parsedGame, err := a.parseGameWithoutActions(g)
+	if err != nil {
+		return game{}, err
+	}
+	return parsedGame.withActions(), nil
+}
+
+// parseGameWithoutActions works like parseGame, but it doesn't calculate the game's actions.
+func (a API) parseGameWithoutActions(g InputGame) (game, error) {
+	if a.output.err != nil {
+		return game{}, a.output.err
+	}
 	var (
 		parsedGame game
 		err        error
 	)
 	switch {
 	case g.FENString != "":
-		parsedGame, err = newGameFromFEN(g.FENString)
+		parsedGame, err = parseFEN(g.FENString)
 	case len(g.Board.Board) > 0:
-		parsedGame, err = newGameFromBoard(mapBoardToInternalBoard(g.Board))
+		parsedGame, err = parseBoard(mapBoardToInternalBoard(g.Board))
 	default:
-		var defaultGame, _ = newGameFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		var defaultGame, _ = parseFEN(defaultFEN)
 		parsedGame = defaultGame
 	}
 	if err != nil {
 		return game{}, err
 	}
-	return parsedGame, nil
+
+	// Draw offers can't be described by FEN notation nor the board
+	switch g.DrawOfferedBy {
+	case "Black":
+		parsedGame.isDrawOffered, parsedGame.drawOfferedBy = true, colorBlack
+	case "White":
+		parsedGame.isDrawOffered, parsedGame.drawOfferedBy = true, colorWhite
+	case "":
+	default:
+		return game{}, errInvalidDrawOfferedBy
+	}
+
+	// Neither can previous positions; those before the last capture or pawn movement can't be repeated
+	for i, key := range g.PositionHistory {
+		parsedKey, err := strconv.ParseUint(key, 16, 64)
+		if err != nil {
+			return game{}, errInvalidPositionHistory
+		}
+		if i >= len(g.PositionHistory)-parsedGame.halfMoveClock {
+			parsedGame.positionHistory = append(parsedGame.positionHistory, parsedKey)
+		}
+	}
+
+	// The game keeps the API's workers, so that the actions of the games that follow from it are also calculated with
+	// them
+	parsedGame.workers = a.workers
+	return parsedGame.calculateFlags(), nil
 }
 
 func (a API) parseAction(ia InputAction, g game) (action, error) {
+	nonMoveAction := action{
+		fromPiece:     piece{owner: g.turn()},
+		isResign:      ia.IsResign,
+		isClaimDraw:   ia.IsClaimDraw,
+		isTimeout:     ia.IsTimeout,
+		isOfferDraw:   ia.IsOfferDraw,
+		isAcceptDraw:  ia.IsAcceptDraw,
+		isDeclineDraw: ia.IsDeclineDraw,
+		isAbort:       ia.IsAbort,
+	}
+	if !nonMoveAction.isMove() {
+		for _, action := range g.actions {
+			if action == nonMoveAction {
+				return action, nil
+			}
+		}
+		return action{}, errInvalidActionForGivenGame
+	}
+
+	if ia.UCI != "" {
+		return a.parseUCIAction(ia.UCI, g)
+	}
+	if ia.SAN != "" {
+		return a.parseSANAction(ia.SAN, g)
+	}
+
 	// TODO eventually accept other forms of action input
 	fromXY, err := a.algebraicToXY(strings.ToLower(ia.FromSquare))
 	if err != nil {
@@ -40,7 +106,7 @@ func (a API) parseAction(ia InputAction, g game) (action, error) {
 	}
 
 	for _, action := range g.actions {
-		if action.fromPiece.xy != fromXY || action.toXY != toXY || (action.isPromotion && action.promotionPieceType != promotionPieceType) {
+		if !action.isMove() || action.fromPiece.xy != fromXY || action.toXY != toXY || (action.isPromotion && action.promotionPieceType != promotionPieceType) {
 			continue
 		}
 		return action, nil
@@ -49,6 +115,139 @@ func (a API) parseAction(ia InputAction, g game) (action, error) {
 	return action{}, errInvalidActionForGivenGame
 }
 
+// parseUCIAction resolves a move in UCI notation (e.g. `e2e4` or `e7e8q`) against the game's actions. Castling may be
+// described either as the King moving two squares (e.g. `e1g1`) or as the King taking its own Rook (e.g. `e1h1`), as
+// in Chess960.
+func (a API) parseUCIAction(s string, g game) (action, error) {
+	s = strings.ToLower(s)
+	if len(s) != 4 && len(s) != 5 {
+		return action{}, errInvalidUCI
+	}
+	fromXY, err := a.algebraicToXY(s[0:2])
+	if err != nil {
+		return action{}, err
+	}
+	toXY, err := a.algebraicToXY(s[2:4])
+	if err != nil {
+		return action{}, err
+	}
+	var promotionPieceType pieceType
+	if len(s) == 5 {
+		var ok bool
+		if promotionPieceType, ok = uciLetterToPromotionPieceType[s[4]]; !ok {
+			return action{}, errInvalidUCI
+		}
+	}
+
+	turn := g.turn()
+	isKingTakingOwnRook := fromXY == g.kings[turn].xy && g.pieces[turn][toXY].pieceType == pieceRook && fromXY.y == toXY.y
+	for _, action := range g.actions {
+		if !action.isMove() || action.fromPiece.xy != fromXY || action.promotionPieceType != promotionPieceType {
+			continue
+		}
+		if action.toXY == toXY || (isKingTakingOwnRook && action.isCastle && action.isKingsideCastle == (toXY.x > fromXY.x)) {
+			return action, nil
+		}
+	}
+
+	return action{}, errInvalidActionForGivenGame
+}
+
+// parseSANAction resolves a move in Standard Algebraic Notation (e.g. `Nbd7`, `exd6 e.p.`, `O-O-O+` or `e8=Q#`)
+// against the game's actions. If the move is ambiguous or illegal, the error lists the candidate actions in SAN.
+// SAN always uses English piece letters.
+func (a API) parseSANAction(s string, g game) (action, error) {
+	gss, aps := newNotationParserAlgebraic(characteristics{usesPieceLetters: []string{"en"}}).matchActions(g, strings.TrimSpace(s))
+	switch {
+	case len(aps) == 0:
+		return action{}, errInvalidSAN
+	case len(gss) == 1:
+		return gss[0].a, nil
+	case len(gss) > 1:
+		return action{}, fmt.Errorf("%w: %v could be any of %v", errAmbiguousSAN, s, emitSANs(g, gss))
+	}
+
+	// The candidates are the actions of the same piece type to the same square, or else any of that piece type
+	var candidates []gameStep
+	for _, relaxed := range []func(actionPattern) actionPattern{actionPattern.samePieceAndDestination, actionPattern.samePiece} {
+		for _, action := range g.actions {
+			for _, ap := range aps {
+				if relaxedPattern := relaxed(ap); action.isMove() && relaxedPattern.isMatch(action) {
+					candidates = append(candidates, gameStep{a: action, g: g.doAction(action)})
+					break
+				}
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	return action{}, fmt.Errorf("%w: %v is not a valid action for the game; candidates are %v", errIllegalSAN, s, emitSANs(g, candidates))
+}
+
+// emitSANs renders the actions of the given steps, which follow from the given game, in Standard Algebraic Notation.
+func emitSANs(g game, gss []gameStep) []string {
+	sans := make([]string, len(gss))
+	for i, gs := range gss {
+		sans[i] = emitAlgebraic(g, gs)
+	}
+	return sans
+}
+
+var uciLetterToPromotionPieceType = map[byte]pieceType{
+	'q': pieceQueen,
+	'r': pieceRook,
+	'b': pieceBishop,
+	'n': pieceKnight,
+}
+
+func (a API) parseOutputAction(oa OutputAction, g game) (action, error) {
+	return a.parseAction(InputAction{
+		FromSquare:         oa.FromPieceSquare,
+		ToSquare:           oa.ToSquare,
+		PromotionPieceType: oa.PromotionPieceType,
+		UCI:                oa.UCI,
+		IsResign:           oa.IsResign,
+		IsClaimDraw:        oa.IsClaimDraw,
+		IsTimeout:          oa.IsTimeout,
+		IsOfferDraw:        oa.IsOfferDraw,
+		IsAcceptDraw:       oa.IsAcceptDraw,
+		IsDeclineDraw:      oa.IsDeclineDraw,
+		IsAbort:            oa.IsAbort,
+	}, g)
+}
+
+// parseOutputGameSteps replays the given steps (and recursively, their variations) starting from the given game.
+// Only the action, comments and NAGs of each step are used; the rest is calculated again.
+func (a API) parseOutputGameSteps(ogss []OutputGameStep, g game) ([]pgnStep, error) {
+	steps := []pgnStep{}
+	for _, ogs := range ogss {
+		action, err := a.parseOutputAction(ogs.Action, g)
+		if err != nil {
+			return steps, err
+		}
+		step := pgnStep{gameStep: gameStep{a: action, g: g.doAction(action)}, comments: ogs.Comments, nags: ogs.NAGs}
+		for _, ogsVariation := range ogs.Variations {
+			variation, err := a.parseOutputGameSteps(ogsVariation, g)
+			if err != nil {
+				return steps, err
+			}
+			step.variations = append(step.variations, variation)
+		}
+		steps = append(steps, step)
+		g = step.g
+	}
+	return steps, nil
+}
+
+func mapPGNTagsToInternalTags(tags []PGNTag) [][2]string {
+	internalTags := make([][2]string, len(tags))
+	for i, tag := range tags {
+		internalTags[i] = [2]string{tag.Name, tag.Value}
+	}
+	return internalTags
+}
+
 func (a API) algebraicToXY(sq string) (xy, error) {
 	if len(sq) != 2 || sq[0] < 'a' || sq[0] > 'h' || sq[1] < '1' || sq[1] > '8' {
 		return xy{}, errAlgebraicSquareInvalidOrOutOfBounds
@@ -0,0 +1,177 @@
+package api
+
+import "math/bits"
+
+// bitboard is a set of squares, one bit per square. The square of an xy is y*8+x, so bit 0 is a8 and bit 63 is h1.
+type bitboard uint64
+
+func squareOf(c xy) int {
+	return c.y*8 + c.x
+}
+
+func xyOfSquare(sq int) xy {
+	return xy{sq % 8, sq / 8}
+}
+
+func (b bitboard) has(sq int) bool {
+	return b&(1<<uint(sq)) != 0
+}
+
+// squares returns the squares in the bitboard, in ascending order.
+func (b bitboard) squares() []int {
+	sqs := make([]int, 0, bits.OnesCount64(uint64(b)))
+	for b != 0 {
+		sqs = append(sqs, bits.TrailingZeros64(uint64(b)))
+		b &= b - 1
+	}
+	return sqs
+}
+
+// bitboards is the layout of a game, with a bitboard per color and per piece type, which makes calculating
+// movements and threats much cheaper than looking up the pieces maps square by square.
+type bitboards struct {
+	byColor     [2]bitboard
+	byPieceType [7]bitboard
+}
+
+func (g game) toBitboards() bitboards {
+	var bbs bitboards
+	for c, ownerPieces := range g.pieces {
+		for _, p := range ownerPieces {
+			bbs.byColor[c] |= 1 << uint(squareOf(p.xy))
+			bbs.byPieceType[p.pieceType] |= 1 << uint(squareOf(p.xy))
+		}
+	}
+	return bbs
+}
+
+func (bbs bitboards) occupied() bitboard {
+	return bbs.byColor[colorBlack] | bbs.byColor[colorWhite]
+}
+
+func (bbs bitboards) pieces(c color, t pieceType) bitboard {
+	return bbs.byColor[c] & bbs.byPieceType[t]
+}
+
+func (bbs *bitboards) remove(c color, t pieceType, sq int) {
+	bbs.byColor[c] &^= 1 << uint(sq)
+	bbs.byPieceType[t] &^= 1 << uint(sq)
+}
+
+func (bbs *bitboards) place(c color, t pieceType, sq int) {
+	bbs.byColor[c] |= 1 << uint(sq)
+	bbs.byPieceType[t] |= 1 << uint(sq)
+}
+
+// attackersOf returns the pieces of the given color that threaten the given square.
+func (bbs bitboards) attackersOf(sq int, c color) bitboard {
+	occupied := bbs.occupied()
+	return bbs.byColor[c] & ((knightAttacks[sq] & bbs.byPieceType[pieceKnight]) |
+		(kingAttacks[sq] & bbs.byPieceType[pieceKing]) |
+		(pawnAttacks[opponent(c)][sq] & bbs.byPieceType[piecePawn]) |
+		(rookAttacks(sq, occupied) & (bbs.byPieceType[pieceRook] | bbs.byPieceType[pieceQueen])) |
+		(bishopAttacks(sq, occupied) & (bbs.byPieceType[pieceBishop] | bbs.byPieceType[pieceQueen])))
+}
+
+// afterAction updates the bitboards as game.makeAction does with the pieces maps, so that the resulting layout can
+// be checked for threats to the King. Assumes that the action is fully-correctly created.
+func (bbs bitboards) afterAction(a action) bitboards {
+	var (
+		owner = a.fromPiece.owner
+		from  = squareOf(a.fromPiece.xy)
+		to    = squareOf(a.toXY)
+	)
+	if a.isCapture {
+		bbs.remove(opponent(owner), a.capturedPiece.pieceType, squareOf(a.capturedXY()))
+	}
+	bbs.remove(owner, a.fromPiece.pieceType, from)
+	if a.isPromotion {
+		bbs.place(owner, a.promotionPieceType, to)
+	} else {
+		bbs.place(owner, a.fromPiece.pieceType, to)
+	}
+	if a.isCastle {
+		rookFromXY, rookToXY := a.castlingRookXYs()
+		bbs.remove(owner, pieceRook, squareOf(rookFromXY))
+		bbs.place(owner, pieceRook, squareOf(rookToXY))
+	}
+	return bbs
+}
+
+// Directions of the rays used to calculate the movements of Bishop, Rook and Queen. The first four go towards
+// higher squares, and the last four towards lower squares.
+const (
+	rayEast = iota
+	raySouth
+	raySouthEast
+	raySouthWest
+	rayWest
+	rayNorth
+	rayNorthWest
+	rayNorthEast
+)
+
+var rayDeltas = [8]xy{{1, 0}, {0, 1}, {1, 1}, {-1, 1}, {-1, 0}, {0, -1}, {-1, -1}, {1, -1}}
+
+var (
+	knightAttacks [64]bitboard
+	kingAttacks   [64]bitboard
+	pawnAttacks   [2][64]bitboard // By color of the attacking Pawn
+	rays          [8][64]bitboard
+)
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		from := xyOfSquare(sq)
+		for _, delta := range movementDeltasByPieceType[pieceKnight] {
+			if to := from.add(delta); isInBounds(to) {
+				knightAttacks[sq] |= 1 << uint(squareOf(to))
+			}
+		}
+		for _, delta := range movementDeltasByPieceType[pieceKing] {
+			if to := from.add(delta); isInBounds(to) && abs(delta.x) <= 1 { // Castling is not a threat
+				kingAttacks[sq] |= 1 << uint(squareOf(to))
+			}
+		}
+		for _, delta := range []xy{{-1, 1}, {1, 1}} {
+			if to := from.add(delta); isInBounds(to) {
+				pawnAttacks[colorBlack][sq] |= 1 << uint(squareOf(to))
+			}
+		}
+		for _, delta := range []xy{{-1, -1}, {1, -1}} {
+			if to := from.add(delta); isInBounds(to) {
+				pawnAttacks[colorWhite][sq] |= 1 << uint(squareOf(to))
+			}
+		}
+		for dir, delta := range rayDeltas {
+			for to := from.add(delta); isInBounds(to); to = to.add(delta) {
+				rays[dir][sq] |= 1 << uint(squareOf(to))
+			}
+		}
+	}
+}
+
+// rayAttacks returns the squares reachable from the given square in the given direction, up to and including the
+// first occupied square.
+func rayAttacks(dir int, sq int, occupied bitboard) bitboard {
+	attacks := rays[dir][sq]
+	blockers := attacks & occupied
+	if blockers == 0 {
+		return attacks
+	}
+	first := bits.TrailingZeros64(uint64(blockers))
+	if dir >= rayWest {
+		first = 63 - bits.LeadingZeros64(uint64(blockers))
+	}
+	return attacks &^ rays[dir][first]
+}
+
+func rookAttacks(sq int, occupied bitboard) bitboard {
+	return rayAttacks(rayEast, sq, occupied) | rayAttacks(raySouth, sq, occupied) |
+		rayAttacks(rayWest, sq, occupied) | rayAttacks(rayNorth, sq, occupied)
+}
+
+func bishopAttacks(sq int, occupied bitboard) bitboard {
+	return rayAttacks(raySouthEast, sq, occupied) | rayAttacks(raySouthWest, sq, occupied) |
+		rayAttacks(rayNorthWest, sq, occupied) | rayAttacks(rayNorthEast, sq, occupied)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingAttacks(t *testing.T) {
+	ts := []struct {
+		name     string
+		attacks  func(sq int, occupied bitboard) bitboard
+		xy       xy
+		occupied []xy
+		expected []xy
+	}{
+		{
+			name:     "Rook in a corner of an empty board",
+			attacks:  rookAttacks,
+			xy:       xy{0, 7},
+			expected: []xy{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {0, 4}, {0, 5}, {0, 6}, {1, 7}, {2, 7}, {3, 7}, {4, 7}, {5, 7}, {6, 7}, {7, 7}},
+		},
+		{
+			name:     "Rook is blocked in every direction, including the blockers",
+			attacks:  rookAttacks,
+			xy:       xy{3, 3},
+			occupied: []xy{{3, 1}, {3, 4}, {1, 3}, {6, 3}},
+			expected: []xy{{3, 1}, {3, 2}, {3, 4}, {1, 3}, {2, 3}, {4, 3}, {5, 3}, {6, 3}},
+		},
+		{
+			name:     "Bishop is blocked in every direction, including the blockers",
+			attacks:  bishopAttacks,
+			xy:       xy{3, 3},
+			occupied: []xy{{2, 2}, {5, 1}, {1, 5}, {4, 4}},
+			expected: []xy{{2, 2}, {4, 2}, {5, 1}, {2, 4}, {1, 5}, {4, 4}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			var occupied bitboard
+			for _, c := range tc.occupied {
+				occupied |= 1 << uint(squareOf(c))
+			}
+			actual := []xy{}
+			for _, sq := range tc.attacks(squareOf(tc.xy), occupied).squares() {
+				actual = append(actual, xyOfSquare(sq))
+			}
+			assert.ElementsMatch(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestAttackersOf(t *testing.T) {
+	ts := []struct {
+		name     string
+		fen      string
+		xy       xy
+		color    color
+		expected []xy
+	}{
+		{
+			name:     "every kind of attacker",
+			fen:      "4k3/8/8/3q4/8/1n2p3/3K4/4R3 w - - 0 1",
+			xy:       xy{3, 6},
+			color:    colorBlack,
+			expected: []xy{{3, 3}, {1, 5}, {4, 5}},
+		},
+		{
+			name:     "sliding attackers are blocked",
+			fen:      "4k3/8/8/3q4/3P4/8/3K4/4R3 w - - 0 1",
+			xy:       xy{3, 6},
+			color:    colorBlack,
+			expected: []xy{},
+		},
+		{
+			name:     "Pawns only attack diagonally forwards",
+			fen:      "4k3/8/8/8/8/2P1P3/3K4/8 w - - 0 1",
+			xy:       xy{3, 4},
+			color:    colorWhite,
+			expected: []xy{{2, 5}, {4, 5}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			actual := []xy{}
+			for _, sq := range g.toBitboards().attackersOf(squareOf(tc.xy), tc.color).squares() {
+				actual = append(actual, xyOfSquare(sq))
+			}
+			assert.ElementsMatch(t, tc.expected, actual)
+		})
+	}
+}
@@ -40,6 +40,16 @@ var (
 )
 
 func newGameFromBoard(b board) (game, error) {
+	g, err := parseBoard(b)
+	if err != nil {
+		return game{}, err
+	}
+	return g, nil
+}
+
+// parseBoard parses a board into a game, without calculating its actions nor any other flags, so that the game can
+// be set up further before calling game.calculateCriticalFlags.
+func parseBoard(b board) (game, error) {
 	g := game{
 		canWhiteCastle:          b.canWhiteKingsideCastle && b.canWhiteQueensideCastle,
 		canWhiteKingsideCastle:  b.canWhiteKingsideCastle,
@@ -162,6 +172,8 @@ func newGameFromBoard(b board) (game, error) {
 		return game{}, errBoardImpossibleEnPassant
 	}
 
+	g.positionHash = g.calculatePositionHash()
+
 	return g.calculateCriticalFlags(), nil
 }
 
@@ -1,84 +1,248 @@
 package api
 
-// updateBoardLayout updates a game's layout-only (i.e. pieces and kings) after a given action, so that the resulting
-// layout can be checked for checks, checkmates, etc.  This method is meant to be used as a dry-run to decide if the
-// action can actually be executed. Should only be called by piece.buildAction and game.doAction.
+import (
+	"sort"
+	"sync"
+)
+
+// undo is what's needed to revert an action done by game.makeAction, i.e. the action itself, and the parts of the
+// game context that can't be inferred from it.
+type undo struct {
+	action                  action
+	canWhiteCastle          bool
+	canWhiteKingsideCastle  bool
+	canWhiteQueensideCastle bool
+	canBlackCastle          bool
+	canBlackKingsideCastle  bool
+	canBlackQueensideCastle bool
+	halfMoveClock           int
+	fullMoveNumber          int
+	isLastMoveEnPassant     bool
+	enPassantTargetSquare   xy
+	positionHash            uint64
+}
+
+// makeAction executes the given action in place, updating the layout (i.e. pieces and kings), castling rights, en
+// passant, clocks, turn and position hash, and returns what's needed to revert it with unmakeAction. It doesn't allocate, so it's
+// suitable for walking a game or a search tree.
 //
 // Note that this method assumes things like:
-// - The destination xy is within bounds.
-// - The action does not uncover an opponent's check, checkmate, etc.
-// - The destination xy doesn't put fromPiece on top of a friendly piece, or does not jump another piece when invalid.
-func (g game) updateBoardLayout(a action) game {
-	clonedGame := g.clone()
-	opponent := opponent(a.fromPiece.owner)
-
-	// Special case for resignation action, because it doesn't require board changes
-	if a.isResign {
-		return clonedGame
-	}
-
-	// Update fromPiece's properties to reflect action
-	fromPiece := g.pieces[a.fromPiece.owner][a.fromPiece.xy]
+// - The action moves a piece (e.g. it's not a resignation), it's fully-correctly created and it's valid.
+// - The game's pieces maps are not shared with any other game (e.g. they've been cloned), as they're modified.
+// - Nothing else is updated (e.g. checks, checkmates, actions), so call game.calculateCriticalFlags if needed.
+func (g *game) makeAction(a action) undo {
+	u := undo{
+		action:                  a,
+		canWhiteCastle:          g.canWhiteCastle,
+		canWhiteKingsideCastle:  g.canWhiteKingsideCastle,
+		canWhiteQueensideCastle: g.canWhiteQueensideCastle,
+		canBlackCastle:          g.canBlackCastle,
+		canBlackKingsideCastle:  g.canBlackKingsideCastle,
+		canBlackQueensideCastle: g.canBlackQueensideCastle,
+		halfMoveClock:           g.halfMoveClock,
+		fullMoveNumber:          g.fullMoveNumber,
+		isLastMoveEnPassant:     g.isLastMoveEnPassant,
+		enPassantTargetSquare:   g.enPassantTargetSquare,
+		positionHash:            g.positionHash,
+	}
+	lastTurn := a.fromPiece.owner
+
+	// The position hash is updated incrementally: castling rights, en passant and turn are XORed out here and back in
+	// once they're updated, and pieces are XORed out and in as they move
+	hash := g.positionHash ^ g.zobristCastlingKey() ^ g.zobristEnPassantKey() ^ g.zobristTurnKey()
+
+	// Remove captured piece, which is not at the destination in the case of en passant capture
+	if a.isCapture {
+		delete(g.pieces[opponent(lastTurn)], a.capturedXY())
+		hash ^= zobristPieceKey(a.capturedPiece)
+	}
+
+	// Move fromPiece to the destination, updating its properties to reflect the action
+	fromPiece := a.fromPiece
 	fromPiece.xy = a.toXY
 	if a.isPromotion {
 		fromPiece.pieceType = a.promotionPieceType
 	}
+	delete(g.pieces[lastTurn], a.fromPiece.xy)
+	g.pieces[lastTurn][fromPiece.xy] = fromPiece
+	hash ^= zobristPieceKey(a.fromPiece) ^ zobristPieceKey(fromPiece)
+	if fromPiece.pieceType == pieceKing {
+		g.kings[lastTurn] = fromPiece
+	}
 
-	// Remove pieces at {from, to} locations
-	delete(clonedGame.pieces[a.fromPiece.owner], a.fromPiece.xy)
-	delete(clonedGame.pieces[opponent], fromPiece.xy)
-	// Place fromPiece at "to" location
-	clonedGame.pieces[a.fromPiece.owner][fromPiece.xy] = fromPiece
+	// Extra movement in the case of castling
+	if a.isCastle {
+		rookFromXY, rookToXY := a.castlingRookXYs()
+		g.pieces[lastTurn][rookToXY] = piece{pieceType: pieceRook, owner: lastTurn, xy: rookToXY}
+		delete(g.pieces[lastTurn], rookFromXY)
+		hash ^= zobristPieceKey(piece{pieceType: pieceRook, owner: lastTurn, xy: rookFromXY}) ^ zobristPieceKey(g.pieces[lastTurn][rookToXY])
+	}
 
-	// Extra movements and deletions in the case of en passant capture and castling
+	// Castling context update
 	switch {
-	case a.isCapture && g.isLastMoveEnPassant && g.enPassantTargetSquare.eq(a.toXY) && a.fromPiece.owner == colorBlack:
-		delete(clonedGame.pieces[opponent], a.toXY.add(xy{0, -1}))
-	case a.isCapture && g.isLastMoveEnPassant && g.enPassantTargetSquare.eq(a.toXY) && a.fromPiece.owner == colorWhite:
-		delete(clonedGame.pieces[opponent], a.toXY.add(xy{0, 1}))
-	case a.isQueensideCastle && a.fromPiece.owner == colorBlack:
-		newRook := clonedGame.pieces[a.fromPiece.owner][xy{0, 0}]
-		newRook.xy = xy{3, 0}
-		clonedGame.pieces[a.fromPiece.owner][xy{3, 0}] = newRook
-		delete(clonedGame.pieces[a.fromPiece.owner], xy{0, 0})
-	case a.isQueensideCastle && a.fromPiece.owner == colorWhite:
-		newRook := clonedGame.pieces[a.fromPiece.owner][xy{0, 7}]
-		newRook.xy = xy{3, 7}
-		clonedGame.pieces[a.fromPiece.owner][xy{3, 7}] = newRook
-		delete(clonedGame.pieces[a.fromPiece.owner], xy{0, 7})
-	case a.isKingsideCastle && a.fromPiece.owner == colorBlack:
-		newRook := clonedGame.pieces[a.fromPiece.owner][xy{7, 0}]
-		newRook.xy = xy{5, 0}
-		clonedGame.pieces[a.fromPiece.owner][xy{5, 0}] = newRook
-		delete(clonedGame.pieces[a.fromPiece.owner], xy{7, 0})
-	case a.isKingsideCastle && a.fromPiece.owner == colorWhite:
-		newRook := clonedGame.pieces[a.fromPiece.owner][xy{7, 7}]
-		newRook.xy = xy{5, 7}
-		clonedGame.pieces[a.fromPiece.owner][xy{5, 7}] = newRook
-		delete(clonedGame.pieces[a.fromPiece.owner], xy{7, 7})
-	}
-
-	// If it's a king, game.kings needs to be updated
-	if fromPiece.pieceType == pieceKing {
-		clonedGame.kings[a.fromPiece.owner] = fromPiece
+	case lastTurn == colorBlack && (a.isCastle || a.fromPiece.pieceType == pieceKing):
+		g.canBlackCastle = false
+		g.canBlackQueensideCastle = false
+		g.canBlackKingsideCastle = false
+	case lastTurn == colorWhite && (a.isCastle || a.fromPiece.pieceType == pieceKing):
+		g.canWhiteCastle = false
+		g.canWhiteQueensideCastle = false
+		g.canWhiteKingsideCastle = false
+	case lastTurn == colorBlack && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 0, y: 0}:
+		g.canBlackQueensideCastle = false
+		g.canBlackCastle = g.canBlackKingsideCastle
+	case lastTurn == colorBlack && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 7, y: 0}:
+		g.canBlackKingsideCastle = false
+		g.canBlackCastle = g.canBlackQueensideCastle
+	case lastTurn == colorWhite && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 0, y: 7}:
+		g.canWhiteQueensideCastle = false
+		g.canWhiteCastle = g.canWhiteKingsideCastle
+	case lastTurn == colorWhite && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 7, y: 7}:
+		g.canWhiteKingsideCastle = false
+		g.canWhiteCastle = g.canWhiteQueensideCastle
+	}
+
+	// Capturing a Rook that hasn't moved also prevents the opponent from castling on that side
+	switch {
+	case a.isCapture && a.capturedPiece.pieceType == pieceRook && a.toXY == xy{x: 0, y: 0}:
+		g.canBlackQueensideCastle = false
+		g.canBlackCastle = g.canBlackKingsideCastle
+	case a.isCapture && a.capturedPiece.pieceType == pieceRook && a.toXY == xy{x: 7, y: 0}:
+		g.canBlackKingsideCastle = false
+		g.canBlackCastle = g.canBlackQueensideCastle
+	case a.isCapture && a.capturedPiece.pieceType == pieceRook && a.toXY == xy{x: 0, y: 7}:
+		g.canWhiteQueensideCastle = false
+		g.canWhiteCastle = g.canWhiteKingsideCastle
+	case a.isCapture && a.capturedPiece.pieceType == pieceRook && a.toXY == xy{x: 7, y: 7}:
+		g.canWhiteKingsideCastle = false
+		g.canWhiteCastle = g.canWhiteQueensideCastle
+	}
+
+	g.moveNumber++
+	if lastTurn == colorBlack {
+		g.fullMoveNumber++
+	}
+	g.isLastMoveEnPassant = a.isEnPassant
+	if a.isEnPassant {
+		g.enPassantTargetSquare = xy{x: a.toXY.x, y: (a.fromPiece.xy.y + a.toXY.y) / 2}
+	}
+
+	g.halfMoveClock++
+	if a.isCapture || a.fromPiece.pieceType == piecePawn {
+		g.halfMoveClock = 0
+	}
+
+	g.positionHash = hash ^ g.zobristCastlingKey() ^ g.zobristEnPassantKey() ^ g.zobristTurnKey()
+
+	return u
+}
+
+// unmakeAction reverts an action done by makeAction, in place. Actions must be reverted in the inverse order that
+// they were done.
+func (g *game) unmakeAction(u undo) {
+	a := u.action
+	lastTurn := a.fromPiece.owner
+
+	if a.isCastle {
+		rookFromXY, rookToXY := a.castlingRookXYs()
+		g.pieces[lastTurn][rookFromXY] = piece{pieceType: pieceRook, owner: lastTurn, xy: rookFromXY}
+		delete(g.pieces[lastTurn], rookToXY)
 	}
 
-	return clonedGame
+	delete(g.pieces[lastTurn], a.toXY)
+	g.pieces[lastTurn][a.fromPiece.xy] = a.fromPiece
+	if a.fromPiece.pieceType == pieceKing {
+		g.kings[lastTurn] = a.fromPiece
+	}
+
+	if a.isCapture {
+		g.pieces[opponent(lastTurn)][a.capturedXY()] = a.capturedPiece
+	}
+
+	g.canWhiteCastle = u.canWhiteCastle
+	g.canWhiteKingsideCastle = u.canWhiteKingsideCastle
+	g.canWhiteQueensideCastle = u.canWhiteQueensideCastle
+	g.canBlackCastle = u.canBlackCastle
+	g.canBlackKingsideCastle = u.canBlackKingsideCastle
+	g.canBlackQueensideCastle = u.canBlackQueensideCastle
+	g.halfMoveClock = u.halfMoveClock
+	g.fullMoveNumber = u.fullMoveNumber
+	g.isLastMoveEnPassant = u.isLastMoveEnPassant
+	g.enPassantTargetSquare = u.enPassantTargetSquare
+	g.positionHash = u.positionHash
+	g.moveNumber--
 }
 
 func (g game) calculateAllActions() []action {
 	if g.isGameOver {
 		return []action{}
 	}
-	actions := []action{}
-	// TODO these can be checked in parallel
-	for _, piece := range g.pieces[g.turn()] {
-		actions = append(actions, piece.calculateAllActions(g)...)
-	}
+	actions := g.calculateMoves()
 	actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isResign: true})
+	actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isTimeout: true})
+	switch {
+	case !g.isDrawOffered:
+		actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isOfferDraw: true})
+	case g.drawOfferedBy != g.turn(): // Only the opponent of the player who offered the draw may respond to it
+		actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isAcceptDraw: true})
+		actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isDeclineDraw: true})
+	}
+	if g.canAbort() {
+		actions = append(actions, action{fromPiece: piece{owner: g.turn()}, isAbort: true})
+	}
 	return actions
 }
 
+var (
+	openingPositionKeysOnce sync.Once
+	openingPositionKeys     map[uint64]bool // true for the initial position, false for the positions after White's first move
+)
+
+// canAbort returns true if the game may be aborted, i.e. if both players haven't made their first move yet. That's
+// the case in the initial position, and in the positions after White's first move, as long as the position history
+// doesn't tell that they were reached later (e.g. 1. Nf3 Nf6 2. Ng1 Ng8). Note that the full move number is not
+// enough, because any position can be set up from a FEN string.
+func (g game) canAbort() bool {
+	openingPositionKeysOnce.Do(func() {
+		initialGame, _ := parseFEN(defaultFEN)
+		initialGame = initialGame.calculateFlags()
+		openingPositionKeys = map[uint64]bool{initialGame.positionKey: true}
+		for _, a := range initialGame.calculateMoves() {
+			openingPositionKeys[initialGame.doActionWithoutActions(a).positionKey] = false
+		}
+	})
+	isInitial, ok := openingPositionKeys[g.positionKey]
+	switch {
+	case !ok:
+		return false
+	case isInitial:
+		return len(g.positionHistory) == 0
+	default:
+		return len(g.positionHistory) == 0 || (len(g.positionHistory) == 1 && openingPositionKeys[g.positionHistory[0]])
+	}
+}
+
+// calculateMoves calculates the valid actions that move pieces for the player whose turn it is, regardless of whether
+// the game is over. The actions are sorted by the square of the piece (i.e. a8, b8, ..., h1), then by destination
+// square, then by promotion piece type (Queen, Rook, Bishop, Knight), so that the order is deterministic, and the same
+// if the actions are calculated in parallel by the game's workers.
+func (g game) calculateMoves() []action {
+	bbs := g.toBitboards()
+	squares := bbs.byColor[g.turn()].squares()
+	if g.workers != nil {
+		return g.workers.calculateMoves(g, bbs, squares)
+	}
+	return g.calculateMovesOfSquares(bbs, squares)
+}
+
+func (g game) calculateMovesOfSquares(bbs bitboards, squares []int) []action {
+	moves := []action{}
+	for _, sq := range squares {
+		moves = append(moves, g.pieces[g.turn()][xyOfSquare(sq)].calculateActions(g, bbs)...)
+	}
+	return moves
+}
+
 func (g game) turn() color {
 	if g.moveNumber%2 == 0 {
 		return colorWhite
@@ -90,197 +254,160 @@ func (p piece) calculateAllActions(g game) []action {
 	if g.isGameOver || g.turn() != p.owner || p.pieceType == pieceNone || g.pieces[p.owner][p.xy] != p {
 		return []action{}
 	}
+	return p.calculateActions(g, g.toBitboards())
+}
 
-	// Naive deltas (special case for Pawn)
-	deltas := movementDeltasByPieceType[p.pieceType]
-	switch {
-	case p.pieceType == piecePawn && p.owner == colorBlack:
-		deltas = []xy{{0, 1}, {0, 2}, {-1, 1}, {1, 1}} // Includes en passant and captures
-	case p.pieceType == piecePawn && p.owner == colorWhite:
-		deltas = []xy{{0, -1}, {0, -2}, {-1, -1}, {1, -1}} // Includes en passant and captures
+// calculateActions calculates all valid actions of a piece, given the game and its bitboards. It does an exhaustive
+// check of validity, including en passant, en passant capture, castling, promotions, etc. Assumes that it's the
+// piece owner's turn.
+func (p piece) calculateActions(g game, bbs bitboards) []action {
+	var (
+		from     = squareOf(p.xy)
+		own      = bbs.byColor[p.owner]
+		occupied = bbs.occupied()
+		targets  bitboard
+	)
+	switch p.pieceType {
+	case pieceKnight:
+		targets = knightAttacks[from] &^ own
+	case pieceKing:
+		targets = kingAttacks[from] &^ own
+	case pieceBishop:
+		targets = bishopAttacks(from, occupied) &^ own
+	case pieceRook:
+		targets = rookAttacks(from, occupied) &^ own
+	case pieceQueen:
+		targets = (bishopAttacks(from, occupied) | rookAttacks(from, occupied)) &^ own
+	case piecePawn:
+		targets = p.pawnTargets(g, bbs)
 	}
 
-	// TODO These can be checked in parallel
 	actions := []action{}
-	for _, delta := range deltas {
-		toXY := p.xy.add(delta)
-
-		// This for-loop covers the case of Bishop, Rook and Queen being able to move multiple times on a given delta
-		for {
-			// If this action is a promotion, then 4 possible actions should be created, one for each promotion piece
-			promotionPieces := []pieceType{pieceNone}
-			if p.pieceType == piecePawn && ((p.owner == colorBlack && p.xy.add(delta).y == 7) || (p.owner == colorWhite && p.xy.add(delta).y == 0)) {
-				promotionPieces = []pieceType{pieceQueen, pieceBishop, pieceKnight, pieceRook}
+	for _, to := range targets.squares() {
+		a := action{fromPiece: p, toXY: xyOfSquare(to)}
+		if capturedPiece, ok := g.pieces[opponent(p.owner)][a.toXY]; ok {
+			a.isCapture = true
+			a.capturedPiece = capturedPiece
+		}
+		if p.pieceType == piecePawn {
+			switch {
+			case abs(a.toXY.y-p.xy.y) == 2:
+				a.isEnPassant = true
+			case g.isLastMoveEnPassant && g.enPassantTargetSquare.eq(a.toXY):
+				a.isCapture = true
+				a.isEnPassantCapture = true
+				a.capturedPiece = g.pieces[opponent(p.owner)][xy{x: a.toXY.x, y: p.xy.y}]
 			}
+		}
 
-			var (
-				a   action
-				err error
-			)
-			// This for-loop covers the case of up to 4 actions created due to promotion pieces
-			for _, promotionPiece := range promotionPieces {
-				a, err = p.buildAction(toXY, g, promotionPiece)
-				if err != nil {
-					break
-				}
-				actions = append(actions, a)
+		// If this action is a promotion, then 4 possible actions should be created, one for each promotion piece
+		if p.pieceType == piecePawn && (a.toXY.y == 0 || a.toXY.y == 7) {
+			for _, promotionPieceType := range []pieceType{pieceQueen, pieceRook, pieceBishop, pieceKnight} {
+				a.isPromotion = true
+				a.promotionPieceType = promotionPieceType
+				actions = p.appendIfValid(actions, a, bbs)
 			}
+			continue
+		}
+		actions = p.appendIfValid(actions, a, bbs)
+	}
 
-			toXY = toXY.add(delta)
-			if (p.pieceType != pieceQueen && p.pieceType != pieceBishop && p.pieceType != pieceRook) ||
-				!isInBounds(toXY) ||
-				a.isCapture ||
-				err == errFriendlyPieceInDestination ||
-				err == errPieceInBetween {
-				break
-			}
+	if p.pieceType == pieceKing {
+		castlingActions := p.castlingActions(g, bbs)
+		for _, a := range castlingActions {
+			actions = p.appendIfValid(actions, a, bbs)
+		}
+		// Castling actions are sorted by destination among the rest, like all actions of a piece
+		if len(castlingActions) > 0 {
+			sort.SliceStable(actions, func(i, j int) bool { return squareOf(actions[i].toXY) < squareOf(actions[j].toXY) })
 		}
 	}
 	return actions
 }
 
-// buildAction tries to create an action given a piece, a game, a destination xy and optionally a promotion piece type.
-// It will do an exhaustive check of validity, including en passant, en passant capture, castling, promotions, etc.
-//
-// The only thing that is mostly assumed valid is that the piece's destination is reachable. This is because the
-// piece's deltas and the eventuality of "jumping" over pieces are both calculated by piece.calculateAllActions. For
-// this reason, this method should only be called internally by piece.calculateAllActions.
-func (p piece) buildAction(toXY xy, g game, promotionPieceType pieceType) (action, error) {
-	if !p.isInBounds(toXY) {
-		return action{}, errNotInBounds
-	}
-
-	// There's a friendly piece in the destination position
-	if _, ok := g.pieces[p.owner][toXY]; ok {
-		return action{}, errFriendlyPieceInDestination
-	}
-
-	// There's a friendly/opponent piece between piece.xy and toXY
-	for _, xyBetween := range p.xysTowards(toXY) {
-		if g.pieces[p.owner][xyBetween].pieceType != pieceNone || g.pieces[opponent(p.owner)][xyBetween].pieceType != pieceNone {
-			return action{}, errPieceInBetween
-		}
+// pawnTargets returns the squares that a Pawn can move to, ignoring whether the action leaves the King threatened.
+func (p piece) pawnTargets(g game, bbs bitboards) bitboard {
+	var (
+		occupied = bbs.occupied()
+		forward  = xy{0, 1}
+		startY   = 1
+		targets  bitboard
+	)
+	if p.owner == colorWhite {
+		forward, startY = xy{0, -1}, 6
 	}
 
-	a := action{fromPiece: p, toXY: toXY}
-	opponentPieceAtToXY, hasOpponentPieceAtToXY := g.pieces[opponent(p.owner)][toXY]
-
 	// Edge case: Pawn is the only piece that cannot capture while moving forwards
-	// If any piece (i.e. owner by any player) is either in the destination or in the middle, the action is invalid
-	if p.pieceType == piecePawn && toXY.x == p.xy.x {
-		// Bail if any constraint is not met
-		switch {
-		case !g.isEmptyAt(toXY),
-			abs(toXY.y-p.xy.y) == 2 && p.owner == colorBlack && !g.isEmptyAt(xy{x: toXY.x, y: toXY.y - 1}),
-			abs(toXY.y-p.xy.y) == 2 && p.owner == colorWhite && !g.isEmptyAt(xy{x: toXY.x, y: toXY.y + 1}),
-			abs(toXY.y-p.xy.y) == 2 && p.owner == colorBlack && p.xy.y != 1,
-			abs(toXY.y-p.xy.y) == 2 && p.owner == colorWhite && p.xy.y != 6:
-			return action{}, errPieceBlockingPawn
+	if oneStep := p.xy.add(forward); isInBounds(oneStep) && !occupied.has(squareOf(oneStep)) {
+		targets |= 1 << uint(squareOf(oneStep))
+		if twoSteps := oneStep.add(forward); p.xy.y == startY && !occupied.has(squareOf(twoSteps)) {
+			targets |= 1 << uint(squareOf(twoSteps))
 		}
 	}
 
 	// Edge case: Pawn can only move diagonally if there's an opponent piece in that position, or if it's en passant
-	if p.pieceType == piecePawn && toXY.x != p.xy.x {
-		// Bail if any constraint is not met
-		switch {
-		case abs(toXY.x-p.xy.x) != 1,
-			abs(toXY.y-p.xy.y) != 1,
-			!hasOpponentPieceAtToXY && (!g.isLastMoveEnPassant || !g.enPassantTargetSquare.eq(toXY)):
-			return action{}, errPawnCantCapture
-		}
+	capturable := bbs.byColor[opponent(p.owner)]
+	if g.isLastMoveEnPassant && isInBounds(g.enPassantTargetSquare) {
+		capturable |= 1 << uint(squareOf(g.enPassantTargetSquare))
 	}
+	return targets | (pawnAttacks[p.owner][squareOf(p.xy)] & capturable)
+}
 
-	// Set the isEnPassant flag
-	if p.pieceType == piecePawn && ((p.owner == colorBlack && p.xy.y == 1 && toXY.y == 3) || (p.owner == colorWhite && p.xy.y == 6 && toXY.y == 4)) {
-		a.isEnPassant = true
+// castlingActions returns the castling actions that a King can do, ignoring whether the action leaves the King
+// threatened.
+func (p piece) castlingActions(g game, bbs bitboards) []action {
+	homeY := 0
+	canCastle := [2]bool{castleTypeQueenside: g.canBlackQueensideCastle, castleTypeKingside: g.canBlackKingsideCastle}
+	if p.owner == colorWhite {
+		homeY = 7
+		canCastle = [2]bool{castleTypeQueenside: g.canWhiteQueensideCastle, castleTypeKingside: g.canWhiteKingsideCastle}
 	}
-
-	// Castling context
-	if p.pieceType == pieceKing && abs(toXY.x-p.xy.x) > 1 { // It's a castle attempt
-		// Set castle type context
-		var castleType castleType = castleTypeQueenside
-		if toXY.x == 6 {
-			castleType = castleTypeKingside
-		}
-
-		// Bail if any constraint is not met
-		switch {
-		case p.owner == colorBlack && !g.canBlackCastle,
-			p.owner == colorWhite && !g.canWhiteCastle,
-			p.owner == colorBlack && castleType == castleTypeQueenside && !g.canBlackQueensideCastle,
-			p.owner == colorBlack && castleType == castleTypeKingside && !g.canBlackKingsideCastle,
-			p.owner == colorWhite && castleType == castleTypeQueenside && !g.canWhiteQueensideCastle,
-			p.owner == colorWhite && castleType == castleTypeKingside && !g.canWhiteKingsideCastle,
-			p.owner == colorBlack && (p.xy.y != 0 || toXY.y != 0 || p.xy.x != 4 || (toXY.x != 6 && toXY.x != 2)),
-			p.owner == colorWhite && (p.xy.y != 7 || toXY.y != 7 || p.xy.x != 4 || (toXY.x != 6 && toXY.x != 2)),
-			!g.isEmptyAtAllOf(emptyXYsForCastlingByColorAndCastleType[p.owner][castleType]),
-			g.isAnyXYThreatened(unthreatenedXYsForCastlingByColorAndCastleType[p.owner][castleType], p.owner):
-			return action{}, errCantCastle
-		}
-
-		// Set castling context
-		a.isCastle = true
-		switch {
-		case p.owner == colorBlack && p.xy.y == 0 && toXY.x == 2:
-			a.isQueensideCastle = true
-		case p.owner == colorWhite && p.xy.y == 7 && toXY.x == 2:
-			a.isQueensideCastle = true
-		case p.owner == colorBlack && p.xy.y == 0 && toXY.x == 6:
-			a.isKingsideCastle = true
-		case p.owner == colorWhite && p.xy.y == 7 && toXY.x == 6:
-			a.isKingsideCastle = true
-		}
+	if p.xy != (xy{4, homeY}) || (p.owner == colorBlack && !g.canBlackCastle) || (p.owner == colorWhite && !g.canWhiteCastle) {
+		return []action{}
 	}
 
-	// Set capture context
-	if hasOpponentPieceAtToXY {
-		a.isCapture = true
-		a.capturedPiece = opponentPieceAtToXY
-	}
-	// Set capture context in the case of an en passant capture
-	if p.pieceType == piecePawn && g.isLastMoveEnPassant && g.enPassantTargetSquare.eq(toXY) {
-		a.isCapture = true
-		a.isEnPassantCapture = true
-		switch p.owner {
-		case colorBlack:
-			a.capturedPiece = g.pieces[opponent(p.owner)][xy{x: toXY.x, y: toXY.y - 1}]
-		case colorWhite:
-			a.capturedPiece = g.pieces[opponent(p.owner)][xy{x: toXY.x, y: toXY.y + 1}]
+	actions := []action{}
+	for _, castleType := range []castleType{castleTypeQueenside, castleTypeKingside} {
+		if !canCastle[castleType] || !bbs.isEmptyAtAllOf(emptyXYsForCastlingByColorAndCastleType[p.owner][castleType]) ||
+			bbs.isAnyXYThreatened(unthreatenedXYsForCastlingByColorAndCastleType[p.owner][castleType], p.owner) {
+			continue
 		}
-	}
-
-	// Set promotion context
-	if p.pieceType == piecePawn && ((p.owner == colorBlack && toXY.y == 7) || (p.owner == colorWhite && toXY.y == 0)) {
-		if promotionPieceType == piecePawn || promotionPieceType == pieceKing || promotionPieceType == pieceNone {
-			return action{}, errCantPromote
+		a := action{fromPiece: p, toXY: xy{2, homeY}, isCastle: true, isQueensideCastle: true}
+		if castleType == castleTypeKingside {
+			a = action{fromPiece: p, toXY: xy{6, homeY}, isCastle: true, isKingsideCastle: true}
 		}
-		a.isPromotion = true
-		a.promotionPieceType = promotionPieceType
+		actions = append(actions, a)
 	}
+	return actions
+}
 
-	newGame := g.updateBoardLayout(a)
-
-	// check if moving puts the owner's King in check
-	if len(newGame.kings[p.owner].threatenedBy(newGame)) > 0 { // N.B. this is an expensive operation!
-		return action{}, errActionLeavesKingThreatened
+// appendIfValid appends the action to the actions, unless it leaves the owner's King threatened.
+func (p piece) appendIfValid(actions []action, a action, bbs bitboards) []action {
+	newBBs := bbs.afterAction(a)
+	kingXY := newBBs.pieces(p.owner, pieceKing).squares()
+	if len(kingXY) > 0 && newBBs.attackersOf(kingXY[0], opponent(p.owner)) != 0 {
+		return actions
 	}
-
-	return a, nil
+	return append(actions, a)
 }
 
-func (g game) isEmptyAtAllOf(xys []xy) bool {
+func (bbs bitboards) isEmptyAtAllOf(xys []xy) bool {
+	occupied := bbs.occupied()
 	for _, xy := range xys {
-		if !g.isEmptyAt(xy) {
+		if occupied.has(squareOf(xy)) {
 			return false
 		}
 	}
 	return true
 }
 
-func (g game) isEmptyAt(xy xy) bool {
-	_, blackPieceExists := g.pieces[colorBlack][xy]
-	_, whitePieceExists := g.pieces[colorWhite][xy]
-	return !blackPieceExists && !whitePieceExists
+func (bbs bitboards) isAnyXYThreatened(xys []xy, owner color) bool {
+	for _, xy := range xys {
+		if bbs.attackersOf(squareOf(xy), opponent(owner)) != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // doAction executes the given action on the given game.
@@ -289,63 +416,93 @@ func (g game) isEmptyAt(xy xy) bool {
 // It fully updates the game context.
 // This is an expensive method (due to having to check for check and checkmate), so use only if needed.
 func (g game) doAction(a action) game {
-	newGame := g.updateBoardLayout(a)
+	return g.doActionWithoutActions(a).withActions()
+}
+
+// doActionWithoutActions works like doAction, but it doesn't calculate the actions of the resulting game, which is
+// by far the most expensive part, so it's useful when they're not needed. All other flags are calculated.
+func (g game) doActionWithoutActions(a action) game {
+	newGame := g.clone()
 	lastTurn := g.turn()
 
 	// Special case for resignation action
 	if a.isResign {
 		newGame.isGameOver = true
 		newGame.gameOverWinner = opponent(lastTurn)
+		newGame.gameOverReason = gameOverReasonResignation
 
 		// TODO is it necessary to update other things?
 		return newGame
 	}
 
-	// Castling context update
-	switch {
-	case lastTurn == colorBlack && (a.isCastle || a.fromPiece.pieceType == pieceKing):
-		newGame.canBlackCastle = false
-		newGame.canBlackQueensideCastle = false
-		newGame.canBlackKingsideCastle = false
-	case lastTurn == colorWhite && (a.isCastle || a.fromPiece.pieceType == pieceKing):
-		newGame.canWhiteCastle = false
-		newGame.canWhiteQueensideCastle = false
-		newGame.canWhiteKingsideCastle = false
-	case lastTurn == colorBlack && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 0, y: 0}:
-		newGame.canBlackQueensideCastle = false
-		newGame.canBlackCastle = newGame.canBlackKingsideCastle
-	case lastTurn == colorBlack && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 7, y: 0}:
-		newGame.canBlackKingsideCastle = false
-		newGame.canBlackCastle = newGame.canBlackQueensideCastle
-	case lastTurn == colorWhite && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 0, y: 7}:
-		newGame.canWhiteQueensideCastle = false
-		newGame.canWhiteCastle = newGame.canWhiteKingsideCastle
-	case lastTurn == colorWhite && a.fromPiece.pieceType == pieceRook && a.fromPiece.xy == xy{x: 7, y: 7}:
-		newGame.canWhiteKingsideCastle = false
-		newGame.canWhiteCastle = newGame.canWhiteQueensideCastle
+	// Special case for running out of time; the opponent only wins if they could possibly checkmate
+	if a.isTimeout {
+		newGame.isGameOver = true
+		newGame.gameOverReason = gameOverReasonTimeout
+		newGame.isDraw = !g.canWin(opponent(lastTurn))
+		if !newGame.isDraw {
+			newGame.gameOverWinner = opponent(lastTurn)
+		}
+		return newGame
 	}
 
-	newGame.moveNumber = g.moveNumber + 1
-	if lastTurn == colorBlack {
-		newGame.fullMoveNumber = g.fullMoveNumber + 1
+	// Special case for offering or declining a draw; it's still the same player's turn, so nothing else changes
+	if a.isOfferDraw || a.isDeclineDraw {
+		newGame.isDrawOffered = a.isOfferDraw
+		newGame.drawOfferedBy = lastTurn
+		return newGame.calculateFlags()
 	}
-	newGame.isLastMoveEnPassant = a.isEnPassant
-	if a.isEnPassant && lastTurn == colorBlack {
-		newGame.enPassantTargetSquare = xy{x: a.toXY.x, y: a.toXY.y - 1}
+
+	// Special case for accepting a draw; it's assumed that the opponent offered it
+	if a.isAcceptDraw {
+		newGame.isDraw = true
+		newGame.isGameOver = true
+		newGame.gameOverReason = gameOverReasonAgreement
+		newGame.isDrawOffered = false
+		return newGame
 	}
-	if a.isEnPassant && lastTurn == colorWhite {
-		newGame.enPassantTargetSquare = xy{x: a.toXY.x, y: a.toXY.y + 1}
+
+	// Special case for aborting; the game is over but it has no result
+	if a.isAbort {
+		newGame.isGameOver = true
+		newGame.gameOverReason = gameOverReasonAborted
+		newGame.isDrawOffered = false
+		return newGame
 	}
 
-	newGame.halfMoveClock = g.halfMoveClock + 1
-	if a.isCapture || a.fromPiece.pieceType == piecePawn {
-		newGame.halfMoveClock = 0
+	// Special case for claiming a draw; it's assumed that it can be claimed, so the first reason is the one claimed
+	if a.isClaimDraw {
+		newGame.isDraw = true
+		newGame.isGameOver = true
+		newGame.gameOverReason = g.claimDrawReasons[0]
+		newGame.canClaimDraw = false
+		newGame.claimDrawReasons = []gameOverReason{}
+		return newGame
+	}
+
+	newGame.makeAction(a)
+
+	// A pending draw offer lapses if the player who didn't offer it makes a move instead of responding to it
+	newGame.isDrawOffered = g.isDrawOffered && g.drawOfferedBy == lastTurn
+
+	// Positions before an irreversible action can't be repeated, so they're not kept
+	newGame.positionHistory = []uint64{}
+	if newGame.halfMoveClock > 0 {
+		newGame.positionHistory = make([]uint64, len(g.positionHistory), len(g.positionHistory)+1)
+		copy(newGame.positionHistory, g.positionHistory)
+		newGame.positionHistory = append(newGame.positionHistory, g.positionKey)
 	}
 
-	return newGame.calculateCriticalFlags()
+	return newGame.calculateFlags()
 }
 
 func (g game) calculateCriticalFlags() game {
+	return g.calculateFlags().withActions()
+}
+
+// calculateFlags calculates everything that calculateCriticalFlags does, except for the game's actions, which is by
+// far the most expensive part. Note that the game's actions are cleared, so call game.withActions if they're needed.
+func (g game) calculateFlags() game {
 	turn := g.turn()
 
 	g.isCheck = false
@@ -354,149 +511,213 @@ func (g game) calculateCriticalFlags() game {
 	g.isDraw = false
 	g.isGameOver = false
 	g.gameOverWinner = -1
+	g.gameOverReason = gameOverReasonNone
 	g.inCheckBy = []piece{}
+	g.isThreefoldRepetition = false
+	g.isFivefoldRepetition = false
+	g.canClaimDraw = false
+	g.claimDrawReasons = []gameOverReason{}
+	g.actions = nil
 
 	g.inCheckBy = g.kings[turn].threatenedBy(g) // This is expensive!
 	if len(g.inCheckBy) > 0 {
 		g.isCheck = true
 	}
 
-	g.actions = g.calculateAllActions() // This is incredibly expensive!
-	if len(g.actions) == 1 && g.actions[0].isResign {
+	if !g.hasAnyMove() {
 		g.isCheckmate = g.isCheck
 		g.isStalemate = !g.isCheck
 	}
 
-	if g.halfMoveClock == 100 {
-		g.isDraw = true // N.B. this forces draw rather than allowing a claim for draw. Is that ok?
+	// N.B. en passant is only considered for the position key if it's possible
+	g.positionKey = g.calculatePositionKey()
+	g.repetitionCount = 1
+	for _, key := range g.positionHistory {
+		if key == g.positionKey {
+			g.repetitionCount++
+		}
+	}
+	g.isThreefoldRepetition = g.repetitionCount >= 3
+	g.isFivefoldRepetition = g.repetitionCount >= 5
+
+	g.canWhiteWinOnTime = g.canWin(colorWhite)
+	g.canBlackWinOnTime = g.canWin(colorBlack)
+
+	// As per FIDE rules, the 75-move rule and fivefold repetition end the game automatically, whereas the 50-move
+	// rule and threefold repetition only allow the player to move to claim a draw. Checkmate takes precedence.
+	switch {
+	case g.isCheckmate:
+		g.gameOverReason = gameOverReasonCheckmate
+		g.gameOverWinner = opponent(turn)
+	case g.isStalemate:
+		g.gameOverReason = gameOverReasonStalemate
+	case g.halfMoveClock >= 150:
+		g.gameOverReason = gameOverReasonSeventyFiveMove
+		g.isDraw = true
+	case g.isFivefoldRepetition:
+		g.gameOverReason = gameOverReasonRepetition
+		g.isDraw = true
+	case !g.canWhiteWinOnTime && !g.canBlackWinOnTime:
+		g.gameOverReason = gameOverReasonInsufficientMaterial
+		g.isDraw = true
 	}
 	if g.isCheckmate || g.isStalemate || g.isDraw {
 		g.isGameOver = true
 	}
-	if g.isCheckmate {
-		g.gameOverWinner = opponent(turn)
+
+	if !g.isGameOver && g.halfMoveClock >= 100 {
+		g.claimDrawReasons = append(g.claimDrawReasons, gameOverReasonFiftyMove)
 	}
+	if !g.isGameOver && g.isThreefoldRepetition {
+		g.claimDrawReasons = append(g.claimDrawReasons, gameOverReasonRepetition)
+	}
+	g.canClaimDraw = len(g.claimDrawReasons) > 0
 
 	return g
 }
 
-func opponent(c color) color {
-	if c == colorBlack {
-		return colorWhite
+// withActions calculates the game's actions. Assumes that the game's flags are already calculated.
+func (g game) withActions() game {
+	g.actions = g.calculateAllActions() // This is incredibly expensive!
+	if g.canClaimDraw {
+		g.actions = append(g.actions, action{fromPiece: piece{owner: g.turn()}, isClaimDraw: true})
 	}
-	return colorBlack
+	return g
 }
 
-func isInBounds(xy xy) bool {
-	return xy.x >= 0 && xy.y >= 0 && xy.x <= 7 && xy.y <= 7
+// result returns the result of the game as in the game termination marker of Portable Game Notation: `1-0` or
+// `0-1` if either player won, `1/2-1/2` if it's a draw, and `*` if the game is not over or was aborted.
+func (g game) result() string {
+	switch {
+	case !g.isGameOver, g.gameOverReason == gameOverReasonAborted:
+		return "*"
+	case g.gameOverWinner == colorWhite:
+		return "1-0"
+	case g.gameOverWinner == colorBlack:
+		return "0-1"
+	}
+	return "1/2-1/2"
 }
 
-func (p piece) isInBounds(xy xy) bool {
-	if !isInBounds(xy) {
-		return false
+// canWin returns true if the given player could checkmate the opponent by any sequence of legal actions, even if
+// the opponent played the worst possible actions. Note that the opponent's pieces may make a checkmate possible,
+// e.g. by blocking their own King. When neither player can win, the position is dead and the game is drawn by
+// insufficient material. Otherwise, a player whose opponent runs out of time only wins if they can win.
+func (g game) canWin(c color) bool {
+	var (
+		knights          int
+		bishopsBySqColor [2]int
+	)
+	for _, p := range g.pieces[c] {
+		switch p.pieceType {
+		case pieceQueen, pieceRook, piecePawn:
+			return true
+		case pieceKnight:
+			knights++
+		case pieceBishop:
+			bishopsBySqColor[(p.xy.x+p.xy.y)%2]++
+		}
 	}
-	if p.pieceType == piecePawn && ((p.owner == colorWhite && p.xy.y == 7) || (p.owner == colorBlack && p.xy.y == 0)) {
+	bishops := bishopsBySqColor[0] + bishopsBySqColor[1]
+
+	switch {
+	case knights+bishops == 0:
 		return false
+	case knights+bishops >= 2 && (knights > 0 || (bishopsBySqColor[0] > 0 && bishopsBySqColor[1] > 0)):
+		return true
 	}
-	return true
-}
 
-func (g game) isAnyXYThreatened(xys []xy, owner color) bool {
-	for _, xy := range xys {
-		if len(g.xyThreatenedBy(xy, owner, false /* checkAllThreats */)) > 0 {
+	// Only a Knight, or only Bishops on squares of the same color: the opponent must help with some piece
+	for _, p := range g.pieces[opponent(c)] {
+		switch {
+		case p.pieceType == pieceKing:
+			continue
+		case knights > 0:
+			return true
+		case p.pieceType != pieceBishop || (bishopsBySqColor[(p.xy.x+p.xy.y)%2] == 0):
 			return true
 		}
 	}
 	return false
 }
 
-func (g game) xyThreatenedBy(sq xy, owner color, checkAllThreats bool) []piece {
-	pieces := []piece{}
-	opponent := opponent(owner)
-
-	// Knights
-	for _, delta := range movementDeltasByPieceType[pieceKnight] {
-		xy := sq.add(delta)
-		if !isInBounds(xy) {
-			continue
-		}
-		if p, ok := g.pieces[opponent][xy]; !ok || p.pieceType != pieceKnight {
-			continue
-		}
-		pieces = append(pieces, g.pieces[opponent][xy])
-		if !checkAllThreats {
-			return pieces
+// hasAnyMove returns true if the player whose turn it is can move any piece. It stops at the first piece that can
+// move, so it's much cheaper than calculating all actions.
+func (g game) hasAnyMove() bool {
+	bbs := g.toBitboards()
+	for _, sq := range bbs.byColor[g.turn()].squares() {
+		if len(g.pieces[g.turn()][xyOfSquare(sq)].calculateActions(g, bbs)) > 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	// Vertical and horizontal (Rook & Queen)
-	for _, delta := range movementDeltasByPieceType[pieceRook] {
-		for sq := sq.add(delta); isInBounds(sq); sq = sq.add(delta) {
-			// There's a friendly piece in this delta; it will block further pieces
-			if _, ok := g.pieces[owner][sq]; ok {
-				break
-			}
-			// There's an opponent piece in this delta but it's not Rook/Queen; it will block further pieces
-			if p, ok := g.pieces[opponent][sq]; ok && p.pieceType != pieceQueen && p.pieceType != pieceRook {
-				break
-			}
-			if p, ok := g.pieces[opponent][sq]; ok && p.pieceType == pieceQueen || p.pieceType == pieceRook {
-				pieces = append(pieces, p)
-				if !checkAllThreats {
-					return pieces
-				}
-				break
+// canCaptureEnPassant returns true if the player whose turn it is can capture en passant, i.e. if any of the Pawns
+// next to the Pawn that just moved two squares can capture it.
+func (g game) canCaptureEnPassant() bool {
+	if !g.isLastMoveEnPassant {
+		return false
+	}
+	var (
+		turn = g.turn()
+		bbs  = g.toBitboards()
+	)
+	for _, p := range g.pieces[turn] {
+		if p.pieceType != piecePawn || abs(p.xy.x-g.enPassantTargetSquare.x) != 1 {
+			continue
+		}
+		for _, a := range p.calculateActions(g, bbs) {
+			if a.isEnPassantCapture {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	// Diagonal (Bishop & Queen)
-	for _, delta := range movementDeltasByPieceType[pieceBishop] {
-		for sq := sq.add(delta); isInBounds(sq); sq = sq.add(delta) {
-			// There's a friendly piece in this delta; it will block further pieces
-			if _, ok := g.pieces[owner][sq]; ok {
-				break
-			}
-			// There's an opponent piece in this delta but it's not Bishop/Queen; it will block further pieces
-			if p, ok := g.pieces[opponent][sq]; ok && p.pieceType != pieceQueen && p.pieceType != pieceBishop {
-				break
-			}
-			if p, ok := g.pieces[opponent][sq]; ok && p.pieceType == pieceQueen || p.pieceType == pieceBishop {
-				pieces = append(pieces, p)
-				if !checkAllThreats {
-					return pieces
-				}
-				break
-			}
-		}
+// calculatePositionKey identifies a position for the purpose of detecting repetitions: two positions are the same if
+// the pieces are placed in the same way, it's the same player's turn, castling rights are the same, and the same
+// en passant captures are possible. It's the position's Zobrist key, except that Polyglot counts the en passant file
+// even if no en passant capture is possible, and repetitions don't.
+func (g game) calculatePositionKey() uint64 {
+	key := g.positionHash
+	if enPassantKey := g.zobristEnPassantKey(); enPassantKey != 0 && !g.canCaptureEnPassant() {
+		key ^= enPassantKey
 	}
+	return key
+}
 
-	// King
-	if abs(sq.x-g.kings[opponent].xy.x) <= 1 && abs(sq.y-g.kings[opponent].xy.y) <= 1 {
-		pieces = append(pieces, g.kings[opponent])
-		if !checkAllThreats {
-			return pieces
-		}
+func opponent(c color) color {
+	if c == colorBlack {
+		return colorWhite
 	}
+	return colorBlack
+}
 
-	// Pawns
-	pawnXYs := []xy{sq.add(xy{-1, 1}), sq.add(xy{1, 1})}
-	if owner == colorWhite {
-		pawnXYs = []xy{sq.add(xy{-1, -1}), sq.add(xy{1, -1})}
+func isInBounds(xy xy) bool {
+	return xy.x >= 0 && xy.y >= 0 && xy.x <= 7 && xy.y <= 7
+}
+
+func (p piece) isInBounds(xy xy) bool {
+	if !isInBounds(xy) {
+		return false
 	}
-	if piece, ok := g.pieces[opponent][pawnXYs[0]]; ok && piece.pieceType == piecePawn {
-		pieces = append(pieces, piece)
-		if !checkAllThreats {
-			return pieces
-		}
+	if p.pieceType == piecePawn && ((p.owner == colorWhite && p.xy.y == 7) || (p.owner == colorBlack && p.xy.y == 0)) {
+		return false
 	}
-	if piece, ok := g.pieces[opponent][pawnXYs[1]]; ok && piece.pieceType == piecePawn {
-		pieces = append(pieces, piece)
+	return true
+}
+
+func (g game) xyThreatenedBy(sq xy, owner color, checkAllThreats bool) []piece {
+	pieces := []piece{}
+	opponent := opponent(owner)
+	for _, attackerSq := range g.toBitboards().attackersOf(squareOf(sq), opponent).squares() {
+		pieces = append(pieces, g.pieces[opponent][xyOfSquare(attackerSq)])
 		if !checkAllThreats {
 			return pieces
 		}
 	}
-
 	return pieces
 }
 
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 )
@@ -28,6 +27,20 @@ type game struct {
 	gameOverWinner          color
 	inCheckBy               []piece
 	actions                 []action
+	positionKey             uint64
+	positionHash            uint64
+	positionHistory         []uint64
+	repetitionCount         int
+	isThreefoldRepetition   bool
+	isFivefoldRepetition    bool
+	canClaimDraw            bool
+	claimDrawReasons        []gameOverReason
+	gameOverReason          gameOverReason
+	canWhiteWinOnTime       bool
+	canBlackWinOnTime       bool
+	isDrawOffered           bool
+	drawOfferedBy           color
+	workers                 *workerPool // If nil, actions are calculated sequentially
 }
 
 func (g game) String() string {
@@ -66,6 +79,7 @@ func (g game) clone() game {
 		canBlackQueensideCastle: g.canBlackQueensideCastle,
 		halfMoveClock:           g.halfMoveClock,
 		fullMoveNumber:          g.fullMoveNumber,
+		isLastMoveEnPassant:     g.isLastMoveEnPassant,
 		enPassantTargetSquare:   g.enPassantTargetSquare,
 		moveNumber:              g.moveNumber,
 		pieces:                  clonedPieces,
@@ -77,6 +91,20 @@ func (g game) clone() game {
 		isGameOver:              g.isGameOver,
 		gameOverWinner:          g.gameOverWinner,
 		inCheckBy:               clonedInCheckBy,
+		positionKey:             g.positionKey,
+		positionHash:            g.positionHash,
+		positionHistory:         g.positionHistory, // N.B. never modified in place, so it's safe to share
+		repetitionCount:         g.repetitionCount,
+		isThreefoldRepetition:   g.isThreefoldRepetition,
+		isFivefoldRepetition:    g.isFivefoldRepetition,
+		canClaimDraw:            g.canClaimDraw,
+		claimDrawReasons:        g.claimDrawReasons, // N.B. never modified in place, so it's safe to share
+		gameOverReason:          g.gameOverReason,
+		canWhiteWinOnTime:       g.canWhiteWinOnTime,
+		canBlackWinOnTime:       g.canBlackWinOnTime,
+		isDrawOffered:           g.isDrawOffered,
+		drawOfferedBy:           g.drawOfferedBy,
+		workers:                 g.workers,
 	}
 }
 
@@ -92,6 +120,12 @@ type action struct {
 	toXY               xy
 	isCapture          bool
 	isResign           bool
+	isClaimDraw        bool
+	isTimeout          bool
+	isOfferDraw        bool
+	isAcceptDraw       bool
+	isDeclineDraw      bool
+	isAbort            bool
 	isPromotion        bool
 	isEnPassant        bool
 	isEnPassantCapture bool
@@ -112,6 +146,18 @@ func (a action) String() string {
 		return fmt.Sprintf("%s's %s at %v captures %s's %s at %v", a.fromPiece.owner, a.fromPiece.pieceType, a.fromPiece.xy.toAlgebraic(), a.capturedPiece.owner, a.capturedPiece.pieceType, a.capturedPiece.xy.toAlgebraic())
 	case a.isResign:
 		return fmt.Sprintf("%s resigns", a.fromPiece.owner)
+	case a.isClaimDraw:
+		return fmt.Sprintf("%s claims a draw", a.fromPiece.owner)
+	case a.isTimeout:
+		return fmt.Sprintf("%s runs out of time", a.fromPiece.owner)
+	case a.isOfferDraw:
+		return fmt.Sprintf("%s offers a draw", a.fromPiece.owner)
+	case a.isAcceptDraw:
+		return fmt.Sprintf("%s accepts the draw offer", a.fromPiece.owner)
+	case a.isDeclineDraw:
+		return fmt.Sprintf("%s declines the draw offer", a.fromPiece.owner)
+	case a.isAbort:
+		return fmt.Sprintf("%s aborts the game", a.fromPiece.owner)
 	case a.isPromotion:
 		return fmt.Sprintf("%s's Pawn at %v promotes to %v", a.fromPiece.owner, a.fromPiece.xy.toAlgebraic(), a.promotionPieceType)
 	case a.isEnPassant:
@@ -125,7 +171,7 @@ func (a action) String() string {
 }
 
 func (a action) DebugString() string {
-	return fmt.Sprintf("%v at (%v, %v) to (%v, %v), isCapture: %v , isResign: %v , isPromotion: %v , isEnPassant: %v , isEnPassantCapture: %v , isCastle: %v , isKingsideCastle: %v , isQueensideCastle: %v, promotionPieceType: %v, capturedPiece: %v at (%v, %v)",
+	return fmt.Sprintf("%v at (%v, %v) to (%v, %v), isCapture: %v , isResign: %v , isClaimDraw: %v , isTimeout: %v , isOfferDraw: %v , isAcceptDraw: %v , isDeclineDraw: %v , isAbort: %v , isPromotion: %v , isEnPassant: %v , isEnPassantCapture: %v , isCastle: %v , isKingsideCastle: %v , isQueensideCastle: %v, promotionPieceType: %v, capturedPiece: %v at (%v, %v)",
 		a.fromPiece.pieceType,
 		a.fromPiece.xy.x,
 		a.fromPiece.xy.y,
@@ -133,6 +179,12 @@ func (a action) DebugString() string {
 		a.toXY.y,
 		a.isCapture,
 		a.isResign,
+		a.isClaimDraw,
+		a.isTimeout,
+		a.isOfferDraw,
+		a.isAcceptDraw,
+		a.isDeclineDraw,
+		a.isAbort,
 		a.isPromotion,
 		a.isEnPassant,
 		a.isEnPassantCapture,
@@ -146,6 +198,27 @@ func (a action) DebugString() string {
 	)
 }
 
+// capturedXY returns the xy of the captured piece, which is not the destination in the case of en passant capture.
+func (a action) capturedXY() xy {
+	if a.isEnPassantCapture {
+		return xy{x: a.toXY.x, y: a.fromPiece.xy.y}
+	}
+	return a.toXY
+}
+
+// castlingRookXYs returns the xys where the Rook is before and after castling.
+func (a action) castlingRookXYs() (xy, xy) {
+	if a.isQueensideCastle {
+		return xy{0, a.toXY.y}, xy{3, a.toXY.y}
+	}
+	return xy{7, a.toXY.y}, xy{5, a.toXY.y}
+}
+
+// isMove returns true if the action moves a piece, as opposed to e.g. resigning or claiming a draw.
+func (a action) isMove() bool {
+	return !a.isResign && !a.isClaimDraw && !a.isTimeout && !a.isOfferDraw && !a.isAcceptDraw && !a.isDeclineDraw && !a.isAbort
+}
+
 type pieceType int
 
 const (
@@ -193,6 +266,48 @@ func (t color) String() string {
 	return "Unknown"
 }
 
+type gameOverReason int
+
+const (
+	gameOverReasonNone = iota
+	gameOverReasonCheckmate
+	gameOverReasonResignation
+	gameOverReasonStalemate
+	gameOverReasonFiftyMove
+	gameOverReasonSeventyFiveMove
+	gameOverReasonRepetition
+	gameOverReasonInsufficientMaterial
+	gameOverReasonAgreement
+	gameOverReasonTimeout
+	gameOverReasonAborted
+)
+
+func (r gameOverReason) String() string {
+	switch r {
+	case gameOverReasonCheckmate:
+		return "checkmate"
+	case gameOverReasonResignation:
+		return "resignation"
+	case gameOverReasonStalemate:
+		return "stalemate"
+	case gameOverReasonFiftyMove:
+		return "fiftyMove"
+	case gameOverReasonSeventyFiveMove:
+		return "seventyFiveMove"
+	case gameOverReasonRepetition:
+		return "repetition"
+	case gameOverReasonInsufficientMaterial:
+		return "insufficientMaterial"
+	case gameOverReasonAgreement:
+		return "agreement"
+	case gameOverReasonTimeout:
+		return "timeout"
+	case gameOverReasonAborted:
+		return "aborted"
+	}
+	return ""
+}
+
 type xy struct {
 	x, y int
 }
@@ -269,17 +384,6 @@ var movementDeltasByPieceType = map[pieceType][]xy{
 	// N.B. Pawn will be dealt with separately, because it's dependant on color
 }
 
-var (
-	errNotInBounds                = errors.New("piece is not in board bounds")
-	errFriendlyPieceInDestination = errors.New("there is a friendly piece in destination")
-	errPieceInBetween             = errors.New("there is a piece between source and destination")
-	errPieceBlockingPawn          = errors.New("there is a piece blocking pawn from moving forwards or en passant")
-	errPawnCantCapture            = errors.New("pawn can't capture because there is no opponent piece (including en passant)")
-	errCantCastle                 = errors.New("king can't castle, because pieces moved, pieces in middle or squares threatened")
-	errCantPromote                = errors.New("pawn can't promote because wrong position or invalid promotion piece type")
-	errActionLeavesKingThreatened = errors.New("action leaves king in a check")
-)
-
 var (
 	emptyXYsForCastlingByColorAndCastleType = map[color]map[castleType][]xy{
 		colorBlack: {
@@ -291,13 +395,14 @@ var (
 			castleTypeKingside:  {{5, 7}, {6, 7}},
 		},
 	}
+	// N.B. when queenside castling, the Rook passes through the b-file, but the King doesn't, so it may be threatened
 	unthreatenedXYsForCastlingByColorAndCastleType = map[color]map[castleType][]xy{
 		colorBlack: {
-			castleTypeQueenside: {{1, 0}, {2, 0}, {3, 0}, {4, 0}},
+			castleTypeQueenside: {{2, 0}, {3, 0}, {4, 0}},
 			castleTypeKingside:  {{4, 0}, {5, 0}, {6, 0}},
 		},
 		colorWhite: {
-			castleTypeQueenside: {{1, 7}, {2, 7}, {3, 7}, {4, 7}},
+			castleTypeQueenside: {{2, 7}, {3, 7}, {4, 7}},
 			castleTypeKingside:  {{4, 7}, {5, 7}, {6, 7}},
 		},
 	}
@@ -8,6 +8,8 @@ import (
 	"strings"
 )
 
+const defaultFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 var (
 	errFENRegexDoesNotMatch              = errors.New("FEN string does not match FEN regexp")
 	errFENRankLargerThan8Squares         = errors.New("FEN string has a rank larger than 8 squares")
@@ -29,9 +31,18 @@ var (
 )
 
 func newGameFromFEN(s string) (game, error) {
+	g, err := parseFEN(s)
+	if err != nil {
+		return game{}, err
+	}
+	return g.calculateCriticalFlags(), nil
+}
+
+// parseFEN parses a FEN string into a game, without calculating its actions nor any other flags, so that the game
+// can be set up further before calling game.calculateCriticalFlags.
+func parseFEN(s string) (game, error) {
 	rxFEN := regexp.MustCompile(`^([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8})\/([1-8rnbqkpRNBQKP]{1,8}) ([wb]) ([KQkq]{0,4}|-) ([a-h][36]|-) ([0-9]{1,3}) ([0-9]{1,3})$`)
 	matches := rxFEN.FindAllStringSubmatch(s, -1)
-	fmt.Print("here",matches)
 	if matches == nil {
 		return game{}, errFENRegexDoesNotMatch
 	}
@@ -155,8 +166,9 @@ func newGameFromFEN(s string) (game, error) {
 		pieces:                  pieces,
 		kings:                   kings,
 	}
+	game.positionHash = game.calculatePositionHash()
 
-	return game.calculateCriticalFlags(), nil
+	return game, nil
 }
 
 // This assumes that Atoi can't fail because the regex capture cannot return a non-number
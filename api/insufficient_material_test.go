@@ -0,0 +1,91 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsufficientMaterial(t *testing.T) {
+	ts := []struct {
+		name                      string
+		fen                       string
+		expectedIsDraw            bool
+		expectedCanWhiteWinOnTime bool
+		expectedCanBlackWinOnTime bool
+	}{
+		{
+			name:           "King vs King",
+			fen:            "4k3/8/8/8/8/8/8/4K3 w - - 0 1",
+			expectedIsDraw: true,
+		},
+		{
+			name:           "King and Bishop vs King",
+			fen:            "4k3/8/8/8/8/8/8/2B1K3 w - - 0 1",
+			expectedIsDraw: true,
+		},
+		{
+			name:           "King vs King and Knight",
+			fen:            "4k1n1/8/8/8/8/8/8/4K3 w - - 0 1",
+			expectedIsDraw: true,
+		},
+		{
+			name:           "Bishops on squares of the same color",
+			fen:            "4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1",
+			expectedIsDraw: true,
+		},
+		{
+			name:           "many Bishops on squares of the same color",
+			fen:            "4k3/8/8/8/8/8/8/B1B1K1B1 w - - 0 1",
+			expectedIsDraw: true,
+		},
+		{
+			name:                      "Bishops on squares of different colors",
+			fen:                       "4k3/8/8/8/8/8/8/1BB1K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+		},
+		{
+			name:                      "opponent's Bishop on a square of a different color could block",
+			fen:                       "4k1b1/8/8/8/8/8/8/2B1K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+			expectedCanBlackWinOnTime: true,
+		},
+		{
+			name:                      "opponent's Knight could block",
+			fen:                       "4k1n1/8/8/8/8/8/8/2N1K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+			expectedCanBlackWinOnTime: true,
+		},
+		{
+			name:                      "King and Knight vs King and Pawn",
+			fen:                       "4k3/7p/8/8/8/8/8/2N1K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+			expectedCanBlackWinOnTime: true,
+		},
+		{
+			name:                      "King and two Knights vs King",
+			fen:                       "4k3/8/8/8/8/8/8/1NN1K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+		},
+		{
+			name:                      "King and Rook vs King",
+			fen:                       "4k3/8/8/8/8/8/8/R3K3 w - - 0 1",
+			expectedCanWhiteWinOnTime: true,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIsDraw, g.isDraw)
+			assert.Equal(t, tc.expectedIsDraw, g.isGameOver)
+			assert.Equal(t, tc.expectedCanWhiteWinOnTime, g.canWhiteWinOnTime)
+			assert.Equal(t, tc.expectedCanBlackWinOnTime, g.canBlackWinOnTime)
+			if tc.expectedIsDraw {
+				assert.Equal(t, gameOverReason(gameOverReasonInsufficientMaterial), g.gameOverReason)
+				assert.Empty(t, g.actions)
+			}
+		})
+	}
+}
@@ -294,7 +294,7 @@ func TestKingActions(t *testing.T) {
 			},
 		},
 		{
-			name: "black king: can't castle queenside because there's a square in check",
+			name: "black king: can castle queenside even though the square the rook passes through is in check",
 			board: board{
 				board: []string{
 					"♜   ♚♝♞♜",
@@ -316,6 +316,7 @@ func TestKingActions(t *testing.T) {
 			xy:    xy{4, 0},
 			actions: []action{
 				{fromPiece: piece{pieceType: pieceKing, owner: colorBlack, xy: xy{4, 0}}, toXY: xy{3, 0}},
+				{fromPiece: piece{pieceType: pieceKing, owner: colorBlack, xy: xy{4, 0}}, toXY: xy{2, 0}, isCastle: true, isQueensideCastle: true},
 			},
 		},
 		{
@@ -567,7 +568,7 @@ func TestKingActions(t *testing.T) {
 			},
 		},
 		{
-			name: "white king: can't castle queenside because there's a square in check",
+			name: "white king: can castle queenside even though the square the rook passes through is in check",
 			board: board{
 				board: []string{
 					" ♞♝♛♚♝♞♜",
@@ -589,6 +590,7 @@ func TestKingActions(t *testing.T) {
 			xy:    xy{4, 7},
 			actions: []action{
 				{fromPiece: piece{pieceType: pieceKing, owner: colorWhite, xy: xy{4, 7}}, toXY: xy{3, 7}},
+				{fromPiece: piece{pieceType: pieceKing, owner: colorWhite, xy: xy{4, 7}}, toXY: xy{2, 7}, isCastle: true, isQueensideCastle: true},
 			},
 		},
 		{
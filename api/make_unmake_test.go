@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeUnmakeAction(t *testing.T) {
+	ts := []struct {
+		name string
+		fen  string
+	}{
+		{name: "default game", fen: defaultFEN},
+		{name: "castling, captures and pins", fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+		{name: "en passant capture", fen: "rnbqkbnr/ppp2ppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3"},
+		{name: "promotions", fen: "n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1"},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			for _, a := range g.actions {
+				if !a.isMove() {
+					continue
+				}
+				expected := g.doAction(a)
+
+				actual := g.clone()
+				u := actual.makeAction(a)
+				assert.Equal(t, expected.toFEN(), actual.toFEN(), a.String())
+				assert.Equal(t, expected.pieces, actual.pieces, a.String())
+				assert.Equal(t, expected.kings, actual.kings, a.String())
+
+				actual.unmakeAction(u)
+				assert.Equal(t, g.toFEN(), actual.toFEN(), a.String())
+				assert.Equal(t, g.pieces, actual.pieces, a.String())
+				assert.Equal(t, g.kings, actual.kings, a.String())
+				assert.Equal(t, g.isLastMoveEnPassant, actual.isLastMoveEnPassant, a.String())
+			}
+		})
+	}
+}
+
+func TestMakeActionCapturingUnmovedRookRemovesCastlingRights(t *testing.T) {
+	g, err := newGameFromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	require.NoError(t, err)
+	for _, a := range g.actions {
+		if a.fromPiece.xy == (xy{x: 0, y: 7}) && a.toXY == (xy{x: 0, y: 0}) {
+			g.makeAction(a)
+			assert.Equal(t, "R3k2r/8/8/8/8/8/8/4K2R b Kk - 0 1", g.toFEN())
+			return
+		}
+	}
+	t.Fatal("Rxa8 not found")
+}
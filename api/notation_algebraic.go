@@ -1,310 +1,315 @@
 package api
 
 import (
-	"fmt"
-	"strconv"
+	"sort"
 	"strings"
 )
 
-func newNotationParserAlgebraic(initialCharacteristics characteristics) *notationParser {
-	var (
-		transitions = map[string]map[string]func([]string) tokenMatch{
-			"full_move_start": {
-				`[\t\f\r ]*([0-9]+)?(\.)?[\t\f\r ]*`: func(ms []string) tokenMatch {
-					var fullMoveNumber *int
-					if len(ms[1]) > 0 {
-						fmn, _ := strconv.Atoi(ms[1])
-						fullMoveNumber = &fmn
-					}
-					var usesFullMoveDot *bool
-					if len(ms[2]) == 1 {
-						usesFullMoveDot = pBool(true)
-					}
-					return tokenMatch{ms[0], nil, characteristics{fullMoveNumber: fullMoveNumber, usesFullMoveDot: usesFullMoveDot}}
-				},
-			},
-			"half_move_separator": {
-				`[\t\f\r ]+`: func(ms []string) tokenMatch {
-					return tokenMatch{ms[0], nil, characteristics{}}
-				},
-			},
-			"full_move_separator": {
-				`([\t\f\r ]*?\n|[\t\f\r ]+)`: func(ms []string) tokenMatch {
-					var usesNewlineAsFullMoveSeparator *bool
-					if strings.Contains(ms[0], "\n") {
-						usesNewlineAsFullMoveSeparator = pBool(true)
-					}
-					return tokenMatch{ms[0], nil, characteristics{usesNewlineAsFullMoveSeparator: usesNewlineAsFullMoveSeparator}}
-				},
-			},
-			"move": {
-				// Move
-				`([QKBNR]?)([a-h])?([1-8])?([a-h])([1-8])(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					sFromPieceType, fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(sFromPieceType),
-						fromX:              fileToPInt(fromSquareFile),
-						fromY:              rankToPInt(fromSquareRank),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isCapture:          pBool(false),
-						isPromotion:        pBool(false),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						isEnPassantCapture: pBool(false),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
-					return tokenMatch{ms[0], &ap, ch}
-				},
-
-				// Capture
-				`([QKBNR])([a-h])?([1-8])?(x|:)?([a-h])([1-8])(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					sFromPieceType, fromSquareFile, fromSquareRank, _, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(sFromPieceType),
-						fromX:              fileToPInt(fromSquareFile),
-						fromY:              rankToPInt(fromSquareRank),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isCapture:          pBool(true),
-						capturedPieceX:     fileToPInt(toSquareFile),
-						capturedPieceY:     rankToPInt(toSquareRank),
-						isPromotion:        pBool(false),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						isEnPassantCapture: pBool(false),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
-					return tokenMatch{ms[0], &ap, ch}
-				},
-
-				// Capture with colon at the end
-				`([QKBNR])([a-h])?([1-8])?([a-h])([1-8]):(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					sFromPieceType, fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(sFromPieceType),
-						fromX:              fileToPInt(fromSquareFile),
-						fromY:              rankToPInt(fromSquareRank),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isCapture:          pBool(true),
-						capturedPieceX:     fileToPInt(toSquareFile),
-						capturedPieceY:     rankToPInt(toSquareRank),
-						isPromotion:        pBool(false),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						isEnPassantCapture: pBool(false),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
-					return tokenMatch{ms[0], &ap, ch}
-				},
+// pieceLetterLanguages are the languages of the letters that name the pieces in Algebraic Notation, in order of
+// precedence: when a whole notation string reads as legal moves in more than one of them (e.g. `1. Rd1` may be a
+// Rook's move in English or a King's move in Spanish), the first one wins.
+var pieceLetterLanguages = []string{"en", "de", "es", "fr", "ru", "figurine"}
 
-				// Capture with pawn, potentially without rank
-				`([a-h])(x|:)?([a-h])([1-8]?)( ?e.p.)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					fromSquareFile, _, toSquareFile, toSquareRank, enPassantCapture, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(""),
-						fromX:              fileToPInt(fromSquareFile),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isEnPassantCapture: pBool(strings.HasSuffix(enPassantCapture, "e.p.")),
-						isCapture:          pBool(true),
-						isPromotion:        pBool(false),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
-					return tokenMatch{ms[0], &ap, ch}
-				},
+// algebraicPieceLetters are the letters that name the pieces in Algebraic Notation, by language. Figurine Algebraic
+// Notation uses the white figurines for both players, but the black ones are also understood by the parser.
+var algebraicPieceLetters = map[string]map[pieceType]string{
+	"en":       pieceTypeToAlgebraicLetter,
+	"de":       {pieceKing: "K", pieceQueen: "D", pieceRook: "T", pieceBishop: "L", pieceKnight: "S", piecePawn: ""},
+	"es":       {pieceKing: "R", pieceQueen: "D", pieceRook: "T", pieceBishop: "A", pieceKnight: "C", piecePawn: ""},
+	"fr":       {pieceKing: "R", pieceQueen: "D", pieceRook: "T", pieceBishop: "F", pieceKnight: "C", piecePawn: ""},
+	"ru":       {pieceKing: "Кр", pieceQueen: "Ф", pieceRook: "Л", pieceBishop: "С", pieceKnight: "К", piecePawn: ""},
+	"figurine": {pieceKing: "♔", pieceQueen: "♕", pieceRook: "♖", pieceBishop: "♗", pieceKnight: "♘", piecePawn: ""},
+}
 
-				// Capture and promotion with pawn, potentially without rank
-				`([a-h])(x|:)?([a-h])([1-8]?)([=\(])([QBNR])\)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					fromSquareFile, _, toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(""),
-						fromX:              fileToPInt(fromSquareFile),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isEnPassantCapture: pBool(false),
-						isCapture:          pBool(true),
-						isPromotion:        pBool(true),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						promotionPieceType: stringToPieceType(sPromotionPieceType),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{
-						usesCheckSymbol:     usesCheckSymbol,
-						usesCheckmateSymbol: usesCheckmateSymbol,
-						usesPromotionSymbol: &promotionSymbol,
-					}
-					return tokenMatch{ms[0], &ap, ch}
-				},
+var blackFigurines = map[string]pieceType{"♚": pieceKing, "♛": pieceQueen, "♜": pieceRook, "♝": pieceBishop, "♞": pieceKnight}
 
-				// Promotion
-				`([a-h])([1-8])([=\(])([QBNR])\)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						fromPieceType:      stringToPieceType(""),
-						toX:                fileToPInt(toSquareFile),
-						toY:                rankToPInt(toSquareRank),
-						isCapture:          pBool(false),
-						isPromotion:        pBool(true),
-						isCastle:           pBool(false),
-						isResign:           pBool(false),
-						isEnPassantCapture: pBool(false),
-						promotionPieceType: stringToPieceType(sPromotionPieceType),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					ch := characteristics{
-						usesCheckSymbol:     usesCheckSymbol,
-						usesCheckmateSymbol: usesCheckmateSymbol,
-						usesPromotionSymbol: &promotionSymbol,
-					}
-					return tokenMatch{ms[0], &ap, ch}
-				},
+func newNotationParserAlgebraic(initialCharacteristics characteristics) *notationParser {
+	moveTransitions := map[string]func([]string) tokenMatch{
+		// Capture with pawn, potentially without rank
+		`([a-h])(x|:)?([a-h])([1-8]?)( ?e.p.)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			fromSquareFile, _, toSquareFile, toSquareRank, enPassantCapture, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			// Without the optional `e.p.` suffix, the capture may or may not be en passant
+			var isEnPassantCapture *bool
+			if strings.HasSuffix(enPassantCapture, "e.p.") {
+				isEnPassantCapture = pBool(true)
+			}
+			ap := actionPattern{
+				fromPieceType:      stringToPieceType(""),
+				fromX:              fileToPInt(fromSquareFile),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isEnPassantCapture: isEnPassantCapture,
+				isCapture:          pBool(true),
+				isPromotion:        pBool(false),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
+			}
+			ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
+			return tokenMatch{ms[0], &ap, ch}
+		},
 
-				// Castling
-				`(0-0|0-0-0|O-O|O-O-O)(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
-					castlingSymbol, threatenSymbol, _ := ms[1], ms[2], ms[3]
-					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
-					ap := actionPattern{
-						isCastle:           pBool(true),
-						isQueensideCastle:  pBool(castlingSymbol == "0-0-0" || castlingSymbol == "O-O-O"),
-						isKingsideCastle:   pBool(castlingSymbol == "0-0" || castlingSymbol == "O-O"),
-						isCapture:          pBool(false),
-						isPromotion:        pBool(false),
-						isResign:           pBool(false),
-						isEnPassantCapture: pBool(false),
-						isCheck:            isCheck,
-						isCheckmate:        isCheckmate,
-					}
-					cs := string(castlingSymbol[0])
-					ch := characteristics{
-						usesCheckSymbol:     usesCheckSymbol,
-						usesCheckmateSymbol: usesCheckmateSymbol,
-						usesCastlingSymbol:  &cs,
-					}
-					return tokenMatch{ms[0], &ap, ch}
-				},
+		// Castling
+		`(0-0-0|0-0|O-O-O|O-O)(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			castlingSymbol, threatenSymbol, _ := ms[1], ms[2], ms[3]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			ap := actionPattern{
+				isCastle:           pBool(true),
+				isQueensideCastle:  pBool(castlingSymbol == "0-0-0" || castlingSymbol == "O-O-O"),
+				isKingsideCastle:   pBool(castlingSymbol == "0-0" || castlingSymbol == "O-O"),
+				isCapture:          pBool(false),
+				isPromotion:        pBool(false),
+				isResign:           pBool(false),
+				isEnPassantCapture: pBool(false),
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
+			}
+			cs := string(castlingSymbol[0])
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesCastlingSymbol:  &cs,
+			}
+			return tokenMatch{ms[0], &ap, ch}
+		},
 
-				// End of game
-				`(1–0|0–1|½–½|resigns|White resigns|Black resigns)`: func(ms []string) tokenMatch {
-					var usesEndGameSymbol string
-					switch ms[1] {
-					case "1-0", "0-1", "½–½":
-						usesEndGameSymbol = "numbers"
-					case "resigns":
-						usesEndGameSymbol = "resigns"
-					case "White resigns", "Black resigns":
-						usesEndGameSymbol = "color resigns"
-					}
-					ap := actionPattern{
-						isResign:           pBool(strings.HasSuffix(usesEndGameSymbol, "resigns")),
-						isPromotion:        pBool(false),
-						isCastle:           pBool(false),
-						isCapture:          pBool(false),
-						isEnPassantCapture: pBool(false),
-					}
-					ch := characteristics{usesEndGameSymbol: &usesEndGameSymbol}
-					if ch.isCheck {
-						ap.isCheck = pBool(true)
-					}
-					if ch.isCheckmate {
-						ap.isCheckmate = pBool(true)
-					}
-					return tokenMatch{ms[0], &ap, ch}
-				},
-			},
+		// End of game
+		`(1–0|0–1|½–½|resigns|White resigns|Black resigns)`: func(ms []string) tokenMatch {
+			var usesEndGameSymbol string
+			switch ms[1] {
+			case "1-0", "0-1", "½–½":
+				usesEndGameSymbol = "numbers"
+			case "resigns":
+				usesEndGameSymbol = "resigns"
+			case "White resigns", "Black resigns":
+				usesEndGameSymbol = "color resigns"
+			}
+			ap := actionPattern{
+				isResign:           pBool(strings.HasSuffix(usesEndGameSymbol, "resigns")),
+				isPromotion:        pBool(false),
+				isCastle:           pBool(false),
+				isCapture:          pBool(false),
+				isEnPassantCapture: pBool(false),
+			}
+			ch := characteristics{usesEndGameSymbol: &usesEndGameSymbol}
+			if ch.isCheck {
+				ap.isCheck = pBool(true)
+			}
+			if ch.isCheckmate {
+				ap.isCheckmate = pBool(true)
+			}
+			return tokenMatch{ms[0], &ap, ch}
+		},
+	}
+	for _, language := range pieceLetterLanguages {
+		for rx, f := range algebraicPieceTransitions(language) {
+			moveTransitions[rx] = f
 		}
+	}
+
+	transitions := withSeparatorTransitions(map[string]map[string]func([]string) tokenMatch{"move": moveTransitions})
+	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+}
 
-		// TODO human-readable error messages here. Also, lacking some context.
-		evolveCharacteristics = func(ch characteristics, sc characteristics) (characteristics, error) {
-			if sc.usesCheckSymbol != nil {
-				if ch.usesCheckSymbol == nil {
-					ch.usesCheckSymbol = sc.usesCheckSymbol
-				} else if *ch.usesCheckSymbol != *sc.usesCheckSymbol {
-					return ch, fmt.Errorf("expecting CheckSymbol %v but found %v", *ch.usesCheckSymbol, *sc.usesCheckSymbol)
-				}
+// algebraicPieceTransitions returns the "move" transitions that name pieces, using the piece letters of the given
+// language. The languages that the piece letters are consistent with become a characteristic of the notation.
+func algebraicPieceTransitions(language string) map[string]func([]string) tokenMatch {
+	pieceRx := pieceLettersRx(language)
+	return map[string]func([]string) tokenMatch{
+		// Move
+		`(` + pieceRx + `)?([a-h])?([1-8])?([a-h])([1-8])(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			sFromPieceType, fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			fromPieceType := letterToPieceType(language, sFromPieceType)
+			ap := actionPattern{
+				fromPieceType:      fromPieceType,
+				fromX:              fileToPInt(fromSquareFile),
+				fromY:              rankToPInt(fromSquareRank),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isCapture:          pBool(false),
+				isPromotion:        pBool(false),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				isEnPassantCapture: pBool(false),
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
 			}
-			if sc.usesCheckmateSymbol != nil {
-				if ch.usesCheckmateSymbol == nil {
-					ch.usesCheckmateSymbol = sc.usesCheckmateSymbol
-				} else if *ch.usesCheckmateSymbol != *sc.usesCheckmateSymbol {
-					return ch, fmt.Errorf("expecting CheckmateSymbol %v but found %v", *ch.usesCheckmateSymbol, *sc.usesCheckmateSymbol)
-				}
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesPieceLetters:    pieceLetterLanguagesOf(sFromPieceType, fromPieceType),
 			}
-			if sc.usesFullMoveDot != nil {
-				if ch.usesFullMoveDot == nil {
-					ch.usesFullMoveDot = sc.usesFullMoveDot
-				} else if *ch.usesFullMoveDot != *sc.usesFullMoveDot {
-					return ch, fmt.Errorf("expecting FullMoveDot %v but found %v", *ch.usesFullMoveDot, *sc.usesFullMoveDot)
-				}
+			return tokenMatch{ms[0], &ap, ch}
+		},
+
+		// Capture
+		`(` + pieceRx + `)([a-h])?([1-8])?(x|:)?([a-h])([1-8])(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			sFromPieceType, fromSquareFile, fromSquareRank, _, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			fromPieceType := letterToPieceType(language, sFromPieceType)
+			ap := actionPattern{
+				fromPieceType:      fromPieceType,
+				fromX:              fileToPInt(fromSquareFile),
+				fromY:              rankToPInt(fromSquareRank),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isCapture:          pBool(true),
+				capturedPieceX:     fileToPInt(toSquareFile),
+				capturedPieceY:     rankToPInt(toSquareRank),
+				isPromotion:        pBool(false),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				isEnPassantCapture: pBool(false),
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
+			}
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesPieceLetters:    pieceLetterLanguagesOf(sFromPieceType, fromPieceType),
 			}
-			if sc.usesNewlineAsFullMoveSeparator != nil {
-				if ch.usesNewlineAsFullMoveSeparator == nil {
-					ch.usesNewlineAsFullMoveSeparator = sc.usesNewlineAsFullMoveSeparator
-				} else if *ch.usesNewlineAsFullMoveSeparator != *sc.usesNewlineAsFullMoveSeparator {
-					return ch, fmt.Errorf("expecting NewlineAsFullMoveSeparator %v but found %v", *ch.usesNewlineAsFullMoveSeparator, *sc.usesNewlineAsFullMoveSeparator)
-				}
+			return tokenMatch{ms[0], &ap, ch}
+		},
+
+		// Capture with colon at the end
+		`(` + pieceRx + `)([a-h])?([1-8])?([a-h])([1-8]):(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			sFromPieceType, fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			fromPieceType := letterToPieceType(language, sFromPieceType)
+			ap := actionPattern{
+				fromPieceType:      fromPieceType,
+				fromX:              fileToPInt(fromSquareFile),
+				fromY:              rankToPInt(fromSquareRank),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isCapture:          pBool(true),
+				capturedPieceX:     fileToPInt(toSquareFile),
+				capturedPieceY:     rankToPInt(toSquareRank),
+				isPromotion:        pBool(false),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				isEnPassantCapture: pBool(false),
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
 			}
-			if sc.usesThreatenSymbol != nil {
-				if ch.usesThreatenSymbol == nil {
-					ch.usesThreatenSymbol = sc.usesThreatenSymbol
-				} else if *ch.usesThreatenSymbol != *sc.usesThreatenSymbol {
-					return ch, fmt.Errorf("expecting ThreatenSymbol %v but found %v", *ch.usesThreatenSymbol, *sc.usesThreatenSymbol)
-				}
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesPieceLetters:    pieceLetterLanguagesOf(sFromPieceType, fromPieceType),
 			}
-			if sc.usesCaptureSymbol != nil {
-				if ch.usesCaptureSymbol == nil {
-					ch.usesCaptureSymbol = sc.usesCaptureSymbol
-				} else if *ch.usesCaptureSymbol != *sc.usesCaptureSymbol {
-					return ch, fmt.Errorf("expecting CaptureSymbol %v but found %v", *ch.usesCaptureSymbol, *sc.usesCaptureSymbol)
-				}
+			return tokenMatch{ms[0], &ap, ch}
+		},
+
+		// Capture and promotion with pawn, potentially without rank
+		`([a-h])(x|:)?([a-h])([1-8]?)([=\(]?)(` + pieceRx + `)\)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			fromSquareFile, _, toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			promotionPieceType := letterToPieceType(language, sPromotionPieceType)
+			ap := actionPattern{
+				fromPieceType:      stringToPieceType(""),
+				fromX:              fileToPInt(fromSquareFile),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isEnPassantCapture: pBool(false),
+				isCapture:          pBool(true),
+				isPromotion:        pBool(true),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				promotionPieceType: promotionPieceType,
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
 			}
-			if sc.usesEndGameSymbol != nil {
-				if ch.usesEndGameSymbol == nil {
-					ch.usesEndGameSymbol = sc.usesEndGameSymbol
-				} else if *ch.usesEndGameSymbol != *sc.usesEndGameSymbol {
-					return ch, fmt.Errorf("expecting EndGameSymbol %v but found %v", *ch.usesEndGameSymbol, *sc.usesEndGameSymbol)
-				}
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesPromotionSymbol: &promotionSymbol,
+				usesPieceLetters:    pieceLetterLanguagesOf(sPromotionPieceType, promotionPieceType),
 			}
-			if sc.usesPromotionSymbol != nil {
-				if ch.usesPromotionSymbol == nil {
-					ch.usesPromotionSymbol = sc.usesPromotionSymbol
-				} else if *ch.usesPromotionSymbol != *sc.usesPromotionSymbol {
-					return ch, fmt.Errorf("expecting PromotionSymbol %v but found %v", *ch.usesPromotionSymbol, *sc.usesPromotionSymbol)
-				}
+			return tokenMatch{ms[0], &ap, ch}
+		},
+
+		// Promotion
+		`([a-h])([1-8])([=\(]?)(` + pieceRx + `)\)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+			toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6]
+			isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+			promotionPieceType := letterToPieceType(language, sPromotionPieceType)
+			ap := actionPattern{
+				fromPieceType:      stringToPieceType(""),
+				toX:                fileToPInt(toSquareFile),
+				toY:                rankToPInt(toSquareRank),
+				isCapture:          pBool(false),
+				isPromotion:        pBool(true),
+				isCastle:           pBool(false),
+				isResign:           pBool(false),
+				isEnPassantCapture: pBool(false),
+				promotionPieceType: promotionPieceType,
+				isCheck:            isCheck,
+				isCheckmate:        isCheckmate,
 			}
-			if sc.usesCastlingSymbol != nil {
-				if ch.usesCastlingSymbol == nil {
-					ch.usesCastlingSymbol = sc.usesCastlingSymbol
-				} else if *ch.usesCastlingSymbol != *sc.usesCastlingSymbol {
-					return ch, fmt.Errorf("expecting CastlingSymbol %v but found %v", *ch.usesCastlingSymbol, *sc.usesCastlingSymbol)
-				}
+			ch := characteristics{
+				usesCheckSymbol:     usesCheckSymbol,
+				usesCheckmateSymbol: usesCheckmateSymbol,
+				usesPromotionSymbol: &promotionSymbol,
+				usesPieceLetters:    pieceLetterLanguagesOf(sPromotionPieceType, promotionPieceType),
 			}
-			// TODO full move number
-			return ch, nil
+			return tokenMatch{ms[0], &ap, ch}
+		},
+	}
+}
+
+// pieceLettersRx returns a regexp alternation of the piece letters of the given language, with the longest ones first
+// so that e.g. Russian `Кр` (King) isn't read as `К` (Knight).
+func pieceLettersRx(language string) string {
+	var letters []string
+	for _, letter := range algebraicPieceLetters[language] {
+		if letter != "" {
+			letters = append(letters, letter)
+		}
+	}
+	if language == "figurine" {
+		for figurine := range blackFigurines {
+			letters = append(letters, figurine)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if len(letters[i]) != len(letters[j]) {
+			return len(letters[i]) > len(letters[j])
 		}
-	)
+		return letters[i] < letters[j]
+	})
+	return strings.Join(letters, "|")
+}
 
-	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+// letterToPieceType returns the piece type named by the given letter in the given language, or a Pawn if the letter
+// is empty.
+func letterToPieceType(language string, letter string) pieceType {
+	if pt, ok := blackFigurines[letter]; ok && language == "figurine" {
+		return pt
+	}
+	for pt, l := range algebraicPieceLetters[language] {
+		if l == letter {
+			return pt
+		}
+	}
+	return pieceNone
+}
+
+// pieceLetterLanguagesOf returns the languages in which the given letter names the given piece type, e.g. `K` names
+// the King in English and German, or nil if the letter is empty, as Pawns are unnamed in every language.
+func pieceLetterLanguagesOf(letter string, pt pieceType) []string {
+	if letter == "" {
+		return nil
+	}
+	var languages []string
+	for _, language := range pieceLetterLanguages {
+		if letterToPieceType(language, letter) == pt {
+			languages = append(languages, language)
+		}
+	}
+	return languages
 }
 
 func stringToPieceType(s string) pieceType {
@@ -338,14 +343,30 @@ func pBool(b bool) *bool {
 	return &b
 }
 
+// The symbols that suffix checks and checkmates, and that precede promoted pieces, which the parsers understand.
+var (
+	checkSymbols     = []string{"+", "†", "ch", "++", "dblch", "dbl ch", "dbl.ch", "disch", "dis ch", "dis.ch"}
+	checkmateSymbols = []string{"#", "mate", "‡", "≠", "X", "x", "×"}
+	promotionSymbols = []string{"=", "(", ""}
+)
+
+func isOneOf(s string, options []string) bool {
+	for _, option := range options {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
 func processThreatenSymbol(threatenSymbol string) (isCheck *bool, isCheckmate *bool, usesCheckSymbol *string, usesCheckmateSymbol *string) {
-	switch threatenSymbol {
-	case "+", "†", "ch", "++", "dblch", "dbl ch", "dbl.ch", "disch", "dis ch", "dis.ch":
+	switch {
+	case isOneOf(threatenSymbol, checkSymbols):
 		isCheck = pBool(true)
 		isCheckmate = nil
 		usesCheckSymbol = &threatenSymbol
 		usesCheckmateSymbol = nil
-	case "#", "mate", "‡", "≠", "X", "x", "×":
+	case isOneOf(threatenSymbol, checkmateSymbols):
 		isCheck = nil
 		isCheckmate = pBool(true)
 		usesCheckSymbol = nil
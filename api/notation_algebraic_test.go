@@ -48,7 +48,20 @@ func TestNotationParserAlgebraic(t *testing.T) {
 				5. Bxd7† Qxd7
 				6. Ne2 dxe4
 				7. 0-0`,
-			expectedErr: fmt.Errorf("expecting CheckSymbol + but found †"),
+			expectedErr: NotationError{
+				Offset:      60,
+				Line:        5,
+				Column:      8,
+				ColumnUTF16: 8,
+				Token:       "Bxd7†",
+				Ply:         9,
+				Expected:    "action",
+				Alternatives: []string{
+					"Bxd7+", "Ba6", "Bc6", "Ba4", "Bc4", "Bd3", "Be2", "Bf1", "exd5", "e5", "a4", "a3", "b3", "f4", "f3", "g4", "g3", "h4", "h3",
+					"Rb1", "Bh6", "Bg5", "Bf4", "Be3", "Bd2", "Qh5", "Qg4", "Qd3", "Qf3", "Qd2", "Qe2", "Kd2", "Ke2", "Kf1", "Nf3", "Nh3", "Ne2",
+				},
+				Message: "expecting CheckSymbol + but found †",
+			},
 		},
 		{
 			fen: "8/8/8/8/8/1k5P/8/2K5 w - - 0 1",
@@ -2011,6 +2024,13 @@ func TestNotationParserAlgebraic(t *testing.T) {
 			expectedFEN: "7Q/8/1K6/8/3k4/8/8/q7 b - - 1 5",
 			expectedErr: nil,
 		},
+		{
+			// An en passant capture without the optional `e.p.` suffix
+			fen:         "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			s:           `3. exf6`,
+			expectedFEN: "rnbqkbnr/ppp1p1pp/5P2/3p4/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3",
+			expectedErr: nil,
+		},
 		{
 			fen: "8/3p4/8/7p/1KPk4/8/B7/8 w - - 0 1",
 			s: `1. c5!  h4
@@ -4075,6 +4095,68 @@ func TestNotationParserAlgebraic(t *testing.T) {
 			expectedFEN: "8/6P1/8/4k3/8/5r2/6K1/8 b - - 1 5",
 			expectedErr: nil,
 		},
+		{
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. e4 e5 2. Sf3 Sc6 3. Lb5 a6 4. La4 Sf6 5. O-O Le7 6. Te1 b5 7. Lb3 d6 8. c3 O-O 9. h3 Dd7`,
+			expectedFEN: "r1b2rk1/2pqbppp/p1np1n2/1p2p3/4P3/1BP2N1P/PP1P1PP1/RNBQR1K1 w - - 1 10",
+		},
+		{
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. d4 d5 2. c4 e6 3. Cc3 Cf6 4. Ag5 Ae7 5. e3 O-O 6. Cf3 Cbd7 7. Tc1 c6 8. Ad3 dxc4 9. Axc4 Cd5 10. Axe7 Dxe7 11. Re2`,
+			expectedFEN: "r1b2rk1/pp1nqppp/2p1p3/3n4/2BP4/2N1PN2/PP2KPPP/2RQ3R b - - 1 11",
+		},
+		{
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. e4 e5 2. Cf3 Cc6 3. Fb5 a6 4. Fa4 Cf6 5. O-O Fe7 6. Te1 b5 7. Fb3 d6 8. c3 O-O 9. h3 Ca5 10. Fc2 c5 11. d4 Dc7`,
+			expectedFEN: "r1b2rk1/2q1bppp/p2p1n2/npp1p3/3PP3/2P2N1P/PPB2PP1/RNBQR1K1 w - - 1 12",
+		},
+		{
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. e4 c5 2. Кf3 d6 3. d4 cxd4 4. Кxd4 Кf6 5. Кc3 a6 6. Сe2 e5 7. Кb3 Сe7 8. O-O O-O 9. Крh1 Фc7`,
+			expectedFEN: "rnb2rk1/1pq1bppp/p2p1n2/4p3/4P3/1NN5/PPP1BPPP/R1BQ1R1K w - - 6 10",
+		},
+		{
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. e4 e5 2. ♘f3 ♞c6 3. ♗c4 ♝c5 4. c3 ♞f6 5. d4 exd4 6. cxd4 ♝b4+ 7. ♗d2 ♝xd2+ 8. ♘bxd2 d5`,
+			expectedFEN: "r1bqk2r/ppp2ppp/2n2n2/3p4/2BPP3/5N2/PP1N1PPP/R2QK2R w KQkq d6 0 9",
+		},
+		{
+			// `K` is the King in English and German, so the language is only known after `Sc6`
+			fen:         "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:           `1. e4 e5 2. Ke2 Sc6 3. Sf3`,
+			expectedFEN: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPPKPPP/RNBQ1B1R b kq - 3 3",
+		},
+		{
+			// `Rd1` may be a Rook's move in English or a King's move in Spanish or French; English is preferred as long as
+			// the whole string is consistent with it
+			fen:         "4k3/8/8/8/8/8/8/R3K3 w - - 0 1",
+			s:           `1. Rd1`,
+			expectedFEN: "4k3/8/8/8/8/8/8/3RK3 b - - 1 1",
+		},
+		{
+			// ...but `Rd7` can only be Black's King's move, so `Rd1` was also a King's move, and then `Tb1` a Rook's move
+			fen:         "4k3/8/8/8/8/8/8/R3K3 w - - 0 1",
+			s:           `1. Rd1 Rd7 2. Tb1`,
+			expectedFEN: "8/3k4/8/8/8/8/8/1R1K4 b - - 3 2",
+		},
+		{
+			fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:   `1. e4 e5 2. Sf3 Nc6`,
+			expectedErr: NotationError{
+				Offset:      16,
+				Line:        1,
+				Column:      17,
+				ColumnUTF16: 17,
+				Token:       "Nc6",
+				Ply:         4,
+				Expected:    "action",
+				Alternatives: []string{
+					"Na6", "Nc6", "Qe7", "Qf6", "Qg5", "Qh4", "Ke7", "Be7", "Bd6", "Bc5", "Bb4", "Ba3", "Ne7", "Nf6", "Nh6",
+					"a6", "a5", "b6", "b5", "c6", "c5", "d6", "d5", "f6", "f5", "g6", "g5", "h6", "h5",
+				},
+				Message: "expecting PieceLetters [de] but found [en]",
+			},
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("Test notation parser algebraic %v", i), func(t *testing.T) {
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// In English Descriptive Notation, files are named after the pieces that start on them (e.g. `KB` is the King's
+// Bishop's file, i.e. the `f` file), and ranks are counted from the side of the player who moves, so `P-K4` is `e4`
+// for White but `e5` for Black. Without the `Q` or `K` prefix, the Rook's, Knight's and Bishop's files are ambiguous.
+var descriptiveFiles = map[string][]int{
+	"QR": {0},
+	"QN": {1},
+	"QB": {2},
+	"Q":  {3},
+	"K":  {4},
+	"KB": {5},
+	"KN": {6},
+	"KR": {7},
+	"R":  {0, 7},
+	"N":  {1, 6},
+	"B":  {2, 5},
+}
+
+var descriptiveFileNames = []string{"QR", "QN", "QB", "Q", "K", "KB", "KN", "KR"}
+
+const (
+	descriptivePieceRx    = `([QK]?(?:Kt|[RNB])?P|[QK]?(?:Kt|[RNB])|[KQ])`
+	descriptiveSquareRx   = `([QK]?(?:Kt|[RNB])|[KQ])([1-8])`
+	descriptivePromoteRx  = `([=/\(]?)(Kt|[QRNB])\)?`
+	descriptiveThreatenRx = `( ?(?:\+|dbl\.? ?ch|dis\.? ?ch|ch|#|mate))?`
+	descriptiveAnnotateRx = `(!!|\?\?|!\?|\?!|!|\?)?`
+)
+
+func newNotationParserDescriptive(initialCharacteristics characteristics) *notationParser {
+	var (
+		transitions = withSeparatorTransitions(map[string]map[string]func([]string) tokenMatch{
+			"move": {
+				// Move, e.g. `P-K4`, `N-KB3` or `P-K8=Q`
+				descriptivePieceRx + `-` + descriptiveSquareRx + `(?:` + descriptivePromoteRx + `)?` + descriptiveThreatenRx + descriptiveAnnotateRx: func(ms []string) tokenMatch {
+					sFromPiece, toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7]
+					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(strings.TrimSpace(threatenSymbol))
+					fromPieceType, fromXs := descriptivePiece(sFromPiece)
+					ap := actionPattern{
+						fromPieceType:      fromPieceType,
+						fromXs:             fromXs,
+						toXs:               descriptiveFile(toSquareFile),
+						toRank:             descriptiveRank(toSquareRank),
+						isCapture:          pBool(false),
+						isPromotion:        pBool(sPromotionPieceType != ""),
+						isCastle:           pBool(false),
+						isResign:           pBool(false),
+						isEnPassantCapture: pBool(false),
+						isCheck:            isCheck,
+						isCheckmate:        isCheckmate,
+					}
+					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
+					if sPromotionPieceType != "" {
+						ap.promotionPieceType, _ = descriptivePiece(sPromotionPieceType)
+						ch.usesPromotionSymbol = &promotionSymbol
+					}
+					return tokenMatch{ms[0], &ap, ch}
+				},
+
+				// Capture, e.g. `PxP`, `BxN`, `NxKP`, `RxR/Q1` or `PxP e.p.`
+				descriptivePieceRx + `[x:]` + descriptivePieceRx + `(?:[/\(]` + descriptiveSquareRx + `\)?)?(?:` + descriptivePromoteRx + `)?( ?e\.p\.)?` + descriptiveThreatenRx + descriptiveAnnotateRx: func(ms []string) tokenMatch {
+					sFromPiece, sCapturedPiece, toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, enPassantCapture, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8], ms[9]
+					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(strings.TrimSpace(threatenSymbol))
+					fromPieceType, fromXs := descriptivePiece(sFromPiece)
+					capturedPieceType, capturedPieceXs := descriptivePiece(sCapturedPiece)
+					// Without the optional `e.p.` suffix, the capture may or may not be en passant
+					var isEnPassantCapture *bool
+					if enPassantCapture != "" {
+						isEnPassantCapture = pBool(true)
+					}
+					ap := actionPattern{
+						fromPieceType:      fromPieceType,
+						fromXs:             fromXs,
+						capturedPieceType:  capturedPieceType,
+						capturedPieceXs:    capturedPieceXs,
+						toXs:               descriptiveFile(toSquareFile),
+						toRank:             descriptiveRank(toSquareRank),
+						isCapture:          pBool(true),
+						isPromotion:        pBool(sPromotionPieceType != ""),
+						isCastle:           pBool(false),
+						isResign:           pBool(false),
+						isEnPassantCapture: isEnPassantCapture,
+						isCheck:            isCheck,
+						isCheckmate:        isCheckmate,
+					}
+					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
+					if sPromotionPieceType != "" {
+						ap.promotionPieceType, _ = descriptivePiece(sPromotionPieceType)
+						ch.usesPromotionSymbol = &promotionSymbol
+					}
+					return tokenMatch{ms[0], &ap, ch}
+				},
+
+				// Castling
+				`(0-0-0|0-0|O-O-O|O-O)` + descriptiveThreatenRx + descriptiveAnnotateRx: func(ms []string) tokenMatch {
+					castlingSymbol, threatenSymbol, _ := ms[1], ms[2], ms[3]
+					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(strings.TrimSpace(threatenSymbol))
+					ap := actionPattern{
+						isCastle:           pBool(true),
+						isQueensideCastle:  pBool(castlingSymbol == "0-0-0" || castlingSymbol == "O-O-O"),
+						isKingsideCastle:   pBool(castlingSymbol == "0-0" || castlingSymbol == "O-O"),
+						isCapture:          pBool(false),
+						isPromotion:        pBool(false),
+						isResign:           pBool(false),
+						isEnPassantCapture: pBool(false),
+						isCheck:            isCheck,
+						isCheckmate:        isCheckmate,
+					}
+					cs := string(castlingSymbol[0])
+					ch := characteristics{
+						usesCheckSymbol:     usesCheckSymbol,
+						usesCheckmateSymbol: usesCheckmateSymbol,
+						usesCastlingSymbol:  &cs,
+					}
+					return tokenMatch{ms[0], &ap, ch}
+				},
+			},
+		})
+	)
+
+	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+}
+
+// descriptivePiece returns the piece type of a piece designation, and the files where the piece may be, or nil if it
+// may be in any file. Pawns may be designated by their file (e.g. `KBP` is the Pawn on the `f` file), and the other
+// pieces by their side of the board (e.g. `QR` is the Rook on the Queen's side, i.e. on the files `a` to `d`).
+func descriptivePiece(s string) (pieceType, []int) {
+	s = strings.Replace(s, "Kt", "N", -1)
+	switch s {
+	case "K":
+		return pieceKing, nil
+	case "Q":
+		return pieceQueen, nil
+	}
+	if strings.HasSuffix(s, "P") {
+		return piecePawn, descriptiveFile(strings.TrimSuffix(s, "P"))
+	}
+	pt := stringToPieceType(s[len(s)-1:])
+	switch s[:len(s)-1] {
+	case "Q":
+		return pt, []int{0, 1, 2, 3}
+	case "K":
+		return pt, []int{4, 5, 6, 7}
+	}
+	return pt, nil
+}
+
+// descriptiveFile returns the files that the given file name may refer to, or nil if it's empty.
+func descriptiveFile(file string) []int {
+	return descriptiveFiles[strings.Replace(file, "Kt", "N", -1)]
+}
+
+func descriptiveRank(rank string) *int {
+	if rank == "" {
+		return nil
+	}
+	v, _ := strconv.Atoi(rank)
+	return &v
+}
+
+// emitDescriptive renders an action in English Descriptive Notation, e.g. `N-KB3`, `PxP e.p.` or `Q-R5 ch`. Of the
+// possible tokens for the action, from the shortest to the longest, it renders the first one that doesn't match any
+// other action.
+func emitDescriptive(prevGame game, gs gameStep) string {
+	a := gs.a
+	if !a.isMove() {
+		return emitNonMove(a)
+	}
+
+	var suffix string
+	switch {
+	case gs.g.isCheckmate:
+		suffix = " mate"
+	case gs.g.isCheck:
+		suffix = " ch"
+	}
+	switch {
+	case a.isKingsideCastle:
+		return "O-O" + suffix
+	case a.isQueensideCastle:
+		return "O-O-O" + suffix
+	}
+
+	tokens := descriptiveTokens(a)
+	parser := newNotationParserDescriptive(characteristics{})
+	for _, token := range tokens {
+		if gss, _ := parser.matchActions(prevGame, token); len(gss) == 1 {
+			return token + suffix
+		}
+	}
+	return tokens[len(tokens)-1] + suffix
+}
+
+// descriptiveTokens returns the possible tokens for a move that isn't castling, from the shortest to the longest.
+func descriptiveTokens(a action) []string {
+	var (
+		pieces   = descriptivePieceNames(a.fromPiece)
+		rank     = strconv.Itoa(relativeRank(a.toXY.y, a.fromPiece.owner))
+		fileName = descriptiveFileNames[a.toXY.x]
+		squares  = []string{fileName[len(fileName)-1:] + rank, fileName + rank}
+		tokens   []string
+	)
+	if fileName == "Q" || fileName == "K" {
+		squares = squares[1:]
+	}
+	if !a.isCapture {
+		for _, p := range pieces {
+			for _, sq := range squares {
+				tokens = append(tokens, p+"-"+sq)
+			}
+		}
+	} else {
+		capturedPieces := descriptivePieceNames(a.capturedPiece)
+		for _, withSquare := range []bool{false, true} {
+			for _, p := range pieces {
+				for _, cp := range capturedPieces {
+					token := p + "x" + cp
+					if withSquare {
+						token += "/" + squares[len(squares)-1]
+					}
+					tokens = append(tokens, token)
+				}
+			}
+		}
+	}
+	for i := range tokens {
+		if a.isPromotion {
+			tokens[i] += "=" + pieceTypeToAlgebraicLetter[a.promotionPieceType]
+		}
+		if a.isEnPassantCapture {
+			tokens[i] += " e.p."
+		}
+	}
+	return tokens
+}
+
+// descriptivePieceNames returns the designations of the given piece, from the shortest to the longest, e.g. `P`, `BP`
+// and `KBP` for a Pawn on the `f` file, or `N` and `QN` for a Knight on the files `a` to `d`.
+func descriptivePieceNames(p piece) []string {
+	switch p.pieceType {
+	case pieceKing, pieceQueen:
+		return []string{pieceTypeToAlgebraicLetter[p.pieceType]}
+	case piecePawn:
+		fileName := descriptiveFileNames[p.xy.x]
+		if len(fileName) == 1 {
+			return []string{"P", fileName + "P"}
+		}
+		return []string{"P", fileName[1:] + "P", fileName + "P"}
+	}
+	wing := "Q"
+	if p.xy.x >= 4 {
+		wing = "K"
+	}
+	return []string{pieceTypeToAlgebraicLetter[p.pieceType], wing + pieceTypeToAlgebraicLetter[p.pieceType]}
+}
+
+// relativeRank returns the rank of the given y coordinate, from 1 to 8, counted from the side of the given player.
+func relativeRank(y int, owner color) int {
+	if owner == colorWhite {
+		return 8 - y
+	}
+	return y + 1
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationParserDescriptive(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		fen                   string
+		s                     string
+		expectedAlgebraic     string // The same match in Algebraic Notation, which must lead to the same game
+		expectedMatchedTokens []string
+		expectsErr            bool
+	}{
+		{
+			name:                  "Ruy Lopez",
+			fen:                   defaultFEN,
+			s:                     "1. P-K4 P-K4\n2. N-KB3 N-QB3\n3. B-N5 P-QR3\n4. B-R4 N-B3\n5. O-O B-K2",
+			expectedAlgebraic:     "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7",
+			expectedMatchedTokens: []string{"P-K4", "P-K4", "N-KB3", "N-QB3", "B-N5", "P-QR3", "B-R4", "N-B3", "O-O", "B-K2"},
+		},
+		{
+			name:              "Kt for Knight",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 P-K4 2. Kt-KB3 Kt-QB3",
+			expectedAlgebraic: "1. e4 e5 2. Nf3 Nc6",
+		},
+		{
+			name:              "Scholar's mate",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 P-K4 2. B-B4 N-QB3 3. Q-R5 N-B3 4. QxBP mate",
+			expectedAlgebraic: "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#",
+		},
+		{
+			name:              "ambiguous capture resolved by a later move",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 P-Q4 2. P-QB4 N-KB3 3. PxP P-K3 4. P-K5",
+			expectedAlgebraic: "1. e4 d5 2. c4 Nf6 3. cxd5 e6 4. e5",
+		},
+		{
+			name:              "qualified capture",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 P-Q4 2. P-QB4 N-KB3 3. KPxP",
+			expectedAlgebraic: "1. e4 d5 2. c4 Nf6 3. exd5",
+		},
+		{
+			name:              "en passant capture and check",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 N-KB3 2. P-K5 P-Q4 3. PxP e.p. BPxP 4. P-Q4 Q-R4 ch 5. B-Q2 QxP 6. RxQ",
+			expectedAlgebraic: "1. e4 Nf6 2. e5 d5 3. exd6 cxd6 4. d4 Qa5+ 5. Bd2 Qxa2 6. Rxa2",
+		},
+		{
+			name:              "capture with destination square",
+			fen:               defaultFEN,
+			s:                 "1. P-K4 P-Q4 2. N-QB3 P-KB4 3. PxP/B5",
+			expectedAlgebraic: "1. e4 d5 2. Nc3 f5 3. exf5",
+		},
+		{
+			name:              "promotion",
+			fen:               "8/8/8/8/8/1k5P/8/2K5 w - - 0 1",
+			s:                 "1. P-R4 K-B5 2. P-R5 K-Q4 3. P-R6 K-K3 4. P-R7 K-B2 5. P-R8=Q",
+			expectedAlgebraic: "1. h4 Kc4 2. h5 Kd5 3. h6 Ke6 4. h7 Kf7 5. h8=Q",
+		},
+		{
+			name:              "queenside castling with check",
+			fen:               "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1",
+			s:                 "1. O-O-O ch",
+			expectedAlgebraic: "1. O-O-O+",
+		},
+		{
+			name:       "ranks are counted from the side of the player who moves",
+			fen:        defaultFEN,
+			s:          "1. P-K4 P-K5",
+			expectsErr: true,
+		},
+		{
+			name:       "inconsistent check symbols",
+			fen:        defaultFEN,
+			s:          "1. P-K4 N-KB3 2. P-K5 P-Q4 3. PxP e.p. BPxP 4. P-Q4 Q-R4 ch 5. B-Q2 Q-N5 6. B-N5+",
+			expectsErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserDescriptive(characteristics{}).parse(g, tc.s)
+			if tc.expectsErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expectedGameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.expectedAlgebraic)
+			require.NoError(t, err)
+			require.Len(t, gameSteps, len(expectedGameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, expectedGameSteps[i].a, gameSteps[i].a)
+			}
+			if tc.expectedMatchedTokens != nil {
+				for i, gameStep := range gameSteps {
+					assert.Equal(t, tc.expectedMatchedTokens[i], gameStep.s)
+				}
+			}
+		})
+	}
+}
+
+func TestEmitDescriptiveRoundTrip(t *testing.T) {
+	matches := []string{
+		"1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7 11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5 Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2",
+		"1. d4 Nf6 2. c4 e6 3. Nc3 Bb4 4. e3 O-O 5. Bd3 d5 6. Nf3 c5 7. O-O Nc6 8. a3 Bxc3 9. bxc3 dxc4 10. Bxc4 Qc7 11. Bd3 e5 12. Qc2 Re8 13. Nxe5 Nxe5 14. dxe5 Qxe5 15. f3 Bd7 16. a4 Rad8",
+		"1. e4 d5 2. exd5 Qxd5 3. Nc3 Qa5 4. d4 Nf6 5. Nf3 Bf5 6. Ne5 c6 7. g4 Be4 8. f3 Bg6 9. h4 Nbd7 10. Nc4 Qc7 11. h5 Be4 12. fxe4 e5 13. dxe5 Nxe5 14. Nxe5 Qxe5 15. Qe2 Bb4 16. Bd2 Bxc3 17. Bxc3 Qxe4",
+	}
+	for i, match := range matches {
+		t.Run(fmt.Sprintf("match %v", i), func(t *testing.T) {
+			g, err := newGameFromFEN(defaultFEN)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, match)
+			require.NoError(t, err)
+			descriptive := ""
+			for i, gs := range emitSteps(emitDescriptive, g, gameSteps) {
+				if i%2 == 0 {
+					descriptive += fmt.Sprintf("%v. ", i/2+1)
+				}
+				descriptive += gs.s + " "
+			}
+			actualGameSteps, err := newNotationParserDescriptive(characteristics{}).parse(g, descriptive)
+			require.NoError(t, err, descriptive)
+			require.Len(t, actualGameSteps, len(gameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, gameSteps[i].a, actualGameSteps[i].a)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"errors"
+	"strings"
+)
+
+var errUnknownNotation = errors.New("unknown notation: please use one of {algebraic|algebraic_de|algebraic_es|algebraic_fr|algebraic_ru|fan|lan|uci|descriptive|iccf}")
+
+// notationEmitter renders the action of a gameStep as a token in some notation. It receives the game prior to the
+// action, because some notations (e.g. Algebraic) require knowing the other available actions to disambiguate.
+type notationEmitter func(prevGame game, gs gameStep) string
+
+var notationEmitters = map[string]notationEmitter{
+	"algebraic":    emitAlgebraic,
+	"algebraic_de": emitStyledAlgebraic(characteristics{usesPieceLetters: []string{"de"}}),
+	"algebraic_es": emitStyledAlgebraic(characteristics{usesPieceLetters: []string{"es"}}),
+	"algebraic_fr": emitStyledAlgebraic(characteristics{usesPieceLetters: []string{"fr"}}),
+	"algebraic_ru": emitStyledAlgebraic(characteristics{usesPieceLetters: []string{"ru"}}),
+	"fan":          emitStyledAlgebraic(characteristics{usesPieceLetters: []string{"figurine"}}),
+	"lan":          emitLongAlgebraic,
+	"uci":          emitUCI,
+	"descriptive":  emitDescriptive,
+	"iccf":         emitICCF,
+}
+
+// styledNotationEmitters build emitters for the notations that can reproduce the style of a notation string, e.g.
+// `0-0` rather than `O-O`.
+var styledNotationEmitters = map[string]func(style characteristics) notationEmitter{
+	"algebraic": emitStyledAlgebraic,
+}
+
+var pieceTypeToAlgebraicLetter = map[pieceType]string{
+	pieceQueen:  "Q",
+	pieceKing:   "K",
+	pieceBishop: "B",
+	pieceKnight: "N",
+	pieceRook:   "R",
+	piecePawn:   "",
+}
+
+// emitSteps renders all gameSteps in the notation of the given emitter, starting from initialGame.
+func emitSteps(emit notationEmitter, initialGame game, gss []gameStep) []gameStep {
+	emitted := make([]gameStep, len(gss))
+	prevGame := initialGame
+	for i, gs := range gss {
+		emitted[i] = gameStep{s: emit(prevGame, gs), a: gs.a, g: gs.g}
+		prevGame = gs.g
+	}
+	return emitted
+}
+
+// emitAlgebraic renders an action in Standard Algebraic Notation, e.g. `Nbxd7+`.
+func emitAlgebraic(prevGame game, gs gameStep) string {
+	if !gs.a.isMove() {
+		return emitNonMove(gs.a)
+	}
+	return algebraicMove(prevGame, gs.a, characteristics{}) + threatenSuffix(gs.g)
+}
+
+// emitStyledAlgebraic returns an emitter of Algebraic Notation in the given style, e.g. `Sbxd7ch` for German piece
+// letters and `ch` as the check symbol, or `♘bxd7+` for figurines. The characteristics that are unknown take the
+// Standard Algebraic Notation style, and the ones about move numbers are ignored, as single actions are rendered.
+func emitStyledAlgebraic(style characteristics) notationEmitter {
+	return func(prevGame game, gs gameStep) string {
+		if !gs.a.isMove() {
+			return emitNonMove(gs.a)
+		}
+		return algebraicMove(prevGame, gs.a, style) + style.threatenSuffix(gs.g)
+	}
+}
+
+// emitLongAlgebraic renders an action in Long Algebraic Notation, e.g. `Nb8xd7+`.
+func emitLongAlgebraic(prevGame game, gs gameStep) string {
+	if !gs.a.isMove() {
+		return emitNonMove(gs.a)
+	}
+	return longAlgebraicMove(gs.a) + threatenSuffix(gs.g)
+}
+
+// algebraicMove renders a move in Algebraic Notation in the given style, without the check or checkmate suffix.
+func algebraicMove(prevGame game, a action, style characteristics) string {
+	var (
+		sb           strings.Builder
+		pieceLetters = style.pieceLetters()
+		castling     = "O"
+	)
+	if style.usesCastlingSymbol != nil {
+		castling = *style.usesCastlingSymbol
+	}
+	switch {
+	case a.isKingsideCastle:
+		sb.WriteString(castling + "-" + castling)
+	case a.isQueensideCastle:
+		sb.WriteString(castling + "-" + castling + "-" + castling)
+	default:
+		sb.WriteString(pieceLetters[a.fromPiece.pieceType])
+		switch {
+		case a.fromPiece.pieceType == piecePawn && a.isCapture:
+			sb.WriteByte("abcdefgh"[a.fromPiece.xy.x])
+		case a.fromPiece.pieceType != piecePawn:
+			sb.WriteString(algebraicDisambiguation(prevGame, a))
+		}
+		if a.isCapture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(a.toXY.toAlgebraic())
+		if a.isPromotion {
+			switch promotionSymbol := style.usesPromotionSymbol; {
+			case promotionSymbol == nil:
+				sb.WriteString("=" + pieceLetters[a.promotionPieceType])
+			case *promotionSymbol == "(":
+				sb.WriteString("(" + pieceLetters[a.promotionPieceType] + ")")
+			default:
+				sb.WriteString(*promotionSymbol + pieceLetters[a.promotionPieceType])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// longAlgebraicMove renders a move in Long Algebraic Notation, without the check or checkmate suffix.
+func longAlgebraicMove(a action) string {
+	var sb strings.Builder
+	switch {
+	case a.isKingsideCastle:
+		sb.WriteString("O-O")
+	case a.isQueensideCastle:
+		sb.WriteString("O-O-O")
+	default:
+		sb.WriteString(pieceTypeToAlgebraicLetter[a.fromPiece.pieceType])
+		sb.WriteString(a.fromPiece.xy.toAlgebraic())
+		if a.isCapture {
+			sb.WriteByte('x')
+		} else {
+			sb.WriteByte('-')
+		}
+		sb.WriteString(a.toXY.toAlgebraic())
+		if a.isPromotion {
+			sb.WriteByte('=')
+			sb.WriteString(pieceTypeToAlgebraicLetter[a.promotionPieceType])
+		}
+	}
+	return sb.String()
+}
+
+// emitUCI renders an action in the notation used by the Universal Chess Interface, e.g. `e7e8q`. Castling is
+// rendered as the King's movement, e.g. `e1g1`.
+func emitUCI(prevGame game, gs gameStep) string {
+	a := gs.a
+	if !a.isMove() {
+		return emitNonMove(a)
+	}
+	s := a.fromPiece.xy.toAlgebraic() + a.toXY.toAlgebraic()
+	if a.isPromotion {
+		s += strings.ToLower(pieceTypeToAlgebraicLetter[a.promotionPieceType])
+	}
+	return s
+}
+
+// emitNonMove renders actions that don't move any piece, e.g. resignation, which are the same in all notations.
+func emitNonMove(a action) string {
+	switch {
+	case a.isResign:
+		return "resigns"
+	case a.isClaimDraw:
+		return "claims draw"
+	case a.isTimeout:
+		return "time out"
+	case a.isOfferDraw:
+		return "offers draw"
+	case a.isAcceptDraw:
+		return "accepts draw"
+	case a.isDeclineDraw:
+		return "declines draw"
+	case a.isAbort:
+		return "aborts"
+	}
+	return ""
+}
+
+// algebraicDisambiguation returns the minimal source square information required so that no other action of the
+// same piece type to the same destination could be mistaken for the given action: nothing, the file, the rank or
+// both.
+func algebraicDisambiguation(prevGame game, a action) string {
+	var ambiguous, sameFile, sameRank bool
+	for _, other := range prevGame.actions {
+		if !other.isMove() || other.fromPiece.pieceType != a.fromPiece.pieceType || other.toXY != a.toXY || other.fromPiece.xy == a.fromPiece.xy {
+			continue
+		}
+		ambiguous = true
+		if other.fromPiece.xy.x == a.fromPiece.xy.x {
+			sameFile = true
+		}
+		if other.fromPiece.xy.y == a.fromPiece.xy.y {
+			sameRank = true
+		}
+	}
+	sq := a.fromPiece.xy.toAlgebraic()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return sq[:1]
+	case !sameRank:
+		return sq[1:]
+	}
+	return sq
+}
+
+// threatenSuffixes returns the check or checkmate suffix of every one of the game's actions, or an empty string for
+// the actions that don't move any piece. Rather than calculating every resulting game, each action is done and
+// undone in place, and only the opponent's King is inspected.
+func (g game) threatenSuffixes() []string {
+	var (
+		suffixes = make([]string, len(g.actions))
+		c        = g.clone() // makeAction modifies the pieces maps in place
+	)
+	for i, a := range g.actions {
+		if !a.isMove() {
+			continue
+		}
+		u := c.makeAction(a)
+		turn := c.turn()
+		if c.toBitboards().attackersOf(squareOf(c.kings[turn].xy), opponent(turn)) != 0 {
+			suffixes[i] = "+"
+			if !c.hasAnyMove() {
+				suffixes[i] = "#"
+			}
+		}
+		c.unmakeAction(u)
+	}
+	return suffixes
+}
+
+// pieceLetters returns the piece letters of the first language of the style, or the English ones if it's unknown.
+func (ch characteristics) pieceLetters() map[pieceType]string {
+	if len(ch.usesPieceLetters) == 0 {
+		return pieceTypeToAlgebraicLetter
+	}
+	return algebraicPieceLetters[ch.usesPieceLetters[0]]
+}
+
+// threatenSuffix returns the check or checkmate suffix of the game in the style, which defaults to `+` and `#`.
+func (ch characteristics) threatenSuffix(g game) string {
+	switch {
+	case g.isCheckmate && ch.usesCheckmateSymbol != nil:
+		return *ch.usesCheckmateSymbol
+	case g.isCheck && !g.isCheckmate && ch.usesCheckSymbol != nil:
+		return *ch.usesCheckSymbol
+	}
+	return threatenSuffix(g)
+}
+
+func threatenSuffix(g game) string {
+	switch {
+	case g.isCheckmate:
+		return "#"
+	case g.isCheck:
+		return "+"
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationEmitters(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		s        string
+		expected map[string][]string
+	}{
+		{
+			fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:   `1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#`,
+			expected: map[string][]string{
+				"algebraic":    {"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"},
+				"lan":          {"e2-e4", "e7-e5", "Bf1-c4", "Nb8-c6", "Qd1-h5", "Ng8-f6", "Qh5xf7#"},
+				"uci":          {"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"},
+				"descriptive":  {"P-K4", "P-K4", "B-B4", "N-QB3", "Q-R5", "N-B3", "QxBP mate"},
+				"algebraic_de": {"e4", "e5", "Lc4", "Sc6", "Dh5", "Sf6", "Dxf7#"},
+				"algebraic_es": {"e4", "e5", "Ac4", "Cc6", "Dh5", "Cf6", "Dxf7#"},
+				"algebraic_fr": {"e4", "e5", "Fc4", "Cc6", "Dh5", "Cf6", "Dxf7#"},
+				"algebraic_ru": {"e4", "e5", "Сc4", "Кc6", "Фh5", "Кf6", "Фxf7#"},
+				"fan":          {"e4", "e5", "♗c4", "♘c6", "♕h5", "♘f6", "♕xf7#"},
+				"iccf":         {"5254", "5755", "6134", "2836", "4185", "7866", "8567"},
+			},
+		},
+		{
+			fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			s:   `1. e4 e6 2. d4 d5 3. Nc3 Bb4 4. Bb5+ Bd7 5. Bxd7+ Qxd7 6. Ne2 dxe4 7. O-O`,
+			expected: map[string][]string{
+				"algebraic": {"e4", "e6", "d4", "d5", "Nc3", "Bb4", "Bb5+", "Bd7", "Bxd7+", "Qxd7", "Ne2", "dxe4", "O-O"},
+				"lan":       {"e2-e4", "e7-e6", "d2-d4", "d7-d5", "Nb1-c3", "Bf8-b4", "Bf1-b5+", "Bc8-d7", "Bb5xd7+", "Qd8xd7", "Ng1-e2", "d5xe4", "O-O"},
+				"uci":       {"e2e4", "e7e6", "d2d4", "d7d5", "b1c3", "f8b4", "f1b5", "c8d7", "b5d7", "d8d7", "g1e2", "d5e4", "e1g1"},
+				"iccf":      {"5254", "5756", "4244", "4745", "2133", "6824", "6125", "3847", "2547", "4847", "7152", "4554", "5171"},
+			},
+		},
+		{
+			fen: "8/8/8/8/8/1k5P/8/2K5 w - - 0 1",
+			s:   `1. h4 Kc4 2. h5 Kd5 3. h6 Ke6 4. h7 Kf7 5. h8=Q`,
+			expected: map[string][]string{
+				"algebraic":    {"h4", "Kc4", "h5", "Kd5", "h6", "Ke6", "h7", "Kf7", "h8=Q"},
+				"uci":          {"h3h4", "b3c4", "h4h5", "c4d5", "h5h6", "d5e6", "h6h7", "e6f7", "h7h8q"},
+				"descriptive":  {"P-R4", "K-B5", "P-R5", "K-Q4", "P-R6", "K-K3", "P-R7", "K-B2", "P-R8=Q"},
+				"algebraic_es": {"h4", "Rc4", "h5", "Rd5", "h6", "Re6", "h7", "Rf7", "h8=D"},
+				"algebraic_ru": {"h4", "Крc4", "h5", "Крd5", "h6", "Крe6", "h7", "Крf7", "h8=Ф"},
+				"fan":          {"h4", "♔c4", "h5", "♔d5", "h6", "♔e6", "h7", "♔f7", "h8=♕"},
+			},
+		},
+		{
+			fen: "8/7k/8/8/8/Q7/8/Q1Q1K3 w - - 0 1",
+			s:   `1. Qa1b2`,
+			expected: map[string][]string{
+				"algebraic": {"Qa1b2"},
+			},
+		},
+		{
+			fen: "1k6/8/8/8/8/1N6/8/1N2K3 w - - 0 1",
+			s:   `1. N1d2`,
+			expected: map[string][]string{
+				"algebraic": {"N1d2"},
+			},
+		},
+		{
+			fen: "1k6/8/8/8/8/5N2/8/1N2K3 w - - 0 1",
+			s:   `1. Nbd2`,
+			expected: map[string][]string{
+				"algebraic": {"Nbd2"},
+			},
+		},
+	}
+	for i, tc := range testCases {
+		for notation, expected := range tc.expected {
+			t.Run(fmt.Sprintf("Test notation emitter %v %v", notation, i), func(t *testing.T) {
+				g, err := newGameFromFEN(tc.fen)
+				require.NoError(t, err)
+				gameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.s)
+				require.NoError(t, err)
+				emitted := emitSteps(notationEmitters[notation], g, gameSteps)
+				actual := make([]string, len(emitted))
+				for i := range emitted {
+					actual[i] = emitted[i].s
+				}
+				assert.Equal(t, expected, actual)
+			})
+		}
+	}
+}
+
+func TestOutputActionsSANAndLAN(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 2",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+		"3k4/8/8/8/8/8/8/R3K3 w Q - 0 1",
+		"k7/8/8/2N5/8/2N3N1/8/7K w - - 0 1",
+	}
+	for _, fen := range fens {
+		t.Run(fen, func(t *testing.T) {
+			og, err := New().ParseGame(InputGame{FENString: fen})
+			require.NoError(t, err)
+			g, err := newGameFromFEN(fen)
+			require.NoError(t, err)
+			moves := g.calculateMoves()
+			require.Len(t, og.Actions, len(moves))
+			for i, a := range moves {
+				gs := gameStep{a: a, g: g.doAction(a)}
+				assert.Equal(t, emitAlgebraic(g, gs), og.Actions[i].SAN)
+				assert.Equal(t, emitLongAlgebraic(g, gs), og.Actions[i].LAN)
+			}
+			for _, a := range og.NonMoveActions {
+				assert.Equal(t, "", a.SAN)
+				assert.Equal(t, "", a.LAN)
+			}
+		})
+	}
+
+	sans := func(oas []OutputAction) []string {
+		ss := []string{}
+		for _, oa := range oas {
+			if oa.SAN != "" {
+				ss = append(ss, oa.SAN)
+			}
+		}
+		return ss
+	}
+	og, err := New().ParseGame(InputGame{FENString: "rnbqkb1r/ppp1pppp/5n2/3p4/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 2"})
+	require.NoError(t, err)
+	assert.Contains(t, sans(og.Actions), "Nbd7")
+	assert.Contains(t, sans(og.Actions), "Nfd7")
+	og, err = New().ParseGame(InputGame{FENString: "k7/4P3/1K6/8/8/8/8/8 w - - 0 1"})
+	require.NoError(t, err)
+	assert.Contains(t, sans(og.Actions), "e8=Q#")
+	assert.Contains(t, sans(og.Actions), "e8=R#")
+	assert.Contains(t, sans(og.Actions), "e8=B")
+	og, err = New().ParseGame(InputGame{FENString: "k7/8/8/2N5/8/2N3N1/8/7K w - - 0 1"})
+	require.NoError(t, err)
+	assert.Contains(t, sans(og.Actions), "Nc3e4")
+	assert.Contains(t, sans(og.Actions), "N5e4")
+	assert.Contains(t, sans(og.Actions), "Nge4")
+
+	// Steps and variations are rendered as per the game they follow from
+	pgn, err := New().ParsePGN("1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 (3... Nd4 4. Qxf7#) 4. Qxf7# 1-0")
+	require.NoError(t, err)
+	require.Len(t, pgn.OutputGameSteps, 7)
+	assert.Equal(t, "Qxf7#", pgn.OutputGameSteps[6].Action.SAN)
+	assert.Equal(t, "Qh5xf7#", pgn.OutputGameSteps[6].Action.LAN)
+	assert.Equal(t, "Nd4", pgn.OutputGameSteps[5].Variations[0][0].Action.SAN)
+	assert.Equal(t, "Qxf7#", pgn.OutputGameSteps[5].Variations[0][1].Action.SAN)
+}
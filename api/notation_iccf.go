@@ -0,0 +1,79 @@
+package api
+
+import (
+	"strconv"
+)
+
+// In ICCF Numeric Notation, used in correspondence chess, files and ranks are both numbered from 1 to 8, so that a
+// move is the origin and the destination squares as four digits, e.g. `5254` is `e2-e4`. Castling is the King's move,
+// e.g. `5171` is `O-O` for White, and promotions append the promoted piece's digit, e.g. `27181` is `b7-a8=Q`.
+var iccfPromotionDigits = map[string]pieceType{
+	"1": pieceQueen,
+	"2": pieceRook,
+	"3": pieceBishop,
+	"4": pieceKnight,
+}
+
+var pieceTypeToICCFPromotionDigit = map[pieceType]string{
+	pieceQueen:  "1",
+	pieceRook:   "2",
+	pieceBishop: "3",
+	pieceKnight: "4",
+}
+
+func newNotationParserICCF(initialCharacteristics characteristics) *notationParser {
+	var (
+		transitions = withSeparatorTransitions(map[string]map[string]func([]string) tokenMatch{
+			"move": {
+				// Move, capture, castling or promotion, e.g. `5254`, `5171` or `27181`
+				`([1-8])([1-8])([1-8])([1-8])([1-4])?`: func(ms []string) tokenMatch {
+					fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, sPromotionPieceType := ms[1], ms[2], ms[3], ms[4], ms[5]
+					// Actions that don't move pieces are on a8 too, so `1818` would match them
+					ap := actionPattern{
+						isMove:             pBool(true),
+						fromX:              iccfFileToPInt(fromSquareFile),
+						fromY:              rankToPInt(fromSquareRank),
+						toX:                iccfFileToPInt(toSquareFile),
+						toY:                rankToPInt(toSquareRank),
+						isPromotion:        pBool(sPromotionPieceType != ""),
+						promotionPieceType: iccfPromotionDigits[sPromotionPieceType],
+						isResign:           pBool(false),
+					}
+					return tokenMatch{ms[0], &ap, characteristics{}}
+				},
+			},
+		})
+	)
+	// Moves are numbers too, so a full move number must be followed by a dot, or be too short to be a move and be
+	// followed by whitespace, e.g. `1. 5254` or `1 5254`, but not `5254 5755`
+	transitions["full_move_start"] = map[string]func([]string) tokenMatch{
+		`[\t\f\r ]*(?:([0-9]+)(\.)|([0-9]{1,3})[\t\f\r ])?[\t\f\r ]*`: func(ms []string) tokenMatch {
+			return fullMoveStartTokenMatch(ms[0], ms[1]+ms[3], ms[2])
+		},
+	}
+
+	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+}
+
+func iccfFileToPInt(file string) *int {
+	v, _ := strconv.Atoi(file)
+	v--
+	return &v
+}
+
+// emitICCF renders an action in ICCF Numeric Notation, e.g. `5254`, `5171` for White's `O-O` or `27181` for `b7-a8=Q`.
+func emitICCF(prevGame game, gs gameStep) string {
+	a := gs.a
+	if !a.isMove() {
+		return emitNonMove(a)
+	}
+	s := iccfSquare(a.fromPiece.xy) + iccfSquare(a.toXY)
+	if a.isPromotion {
+		s += pieceTypeToICCFPromotionDigit[a.promotionPieceType]
+	}
+	return s
+}
+
+func iccfSquare(c xy) string {
+	return strconv.Itoa(c.x+1) + strconv.Itoa(8-c.y)
+}
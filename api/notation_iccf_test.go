@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationParserICCF(t *testing.T) {
+	testCases := []struct {
+		name              string
+		fen               string
+		s                 string
+		expectedAlgebraic string
+		expectsErr        bool
+	}{
+		{
+			name:              "parses an opening with kingside castling for both players",
+			fen:               defaultFEN,
+			s:                 "1. 5254 5755 2. 7163 2836 3. 6125 7866 4. 5171 6857 5. 4152 5878",
+			expectedAlgebraic: "1. e4 e5 2. Nf3 Nc6 3. Bb5 Nf6 4. O-O Be7 5. Qe2 O-O",
+		},
+		{
+			name:              "parses moves without full move numbers",
+			fen:               defaultFEN,
+			s:                 "5254 5755 7163 2836",
+			expectedAlgebraic: "1. e4 e5 2. Nf3 Nc6",
+		},
+		{
+			name:              "parses full moves separated by newlines, without full move numbers",
+			fen:               defaultFEN,
+			s:                 "5254 5755\n7163 2836\n6125",
+			expectedAlgebraic: "1. e4 e5 2. Nf3 Nc6 3. Bb5",
+		},
+		{
+			name:              "parses full move numbers without dots",
+			fen:               defaultFEN,
+			s:                 "1 5254 5755\n2 7163 2836",
+			expectedAlgebraic: "1. e4 e5 2. Nf3 Nc6",
+		},
+		{
+			name:              "parses queenside castling as the King's move",
+			fen:               "r3k3/3p4/8/8/8/8/8/R3K3 w Qq - 0 1",
+			s:                 "1. 5131 5838",
+			expectedAlgebraic: "1. O-O-O O-O-O",
+		},
+		{
+			name:              "parses captures",
+			fen:               defaultFEN,
+			s:                 "1. 5254 4745 2. 5445",
+			expectedAlgebraic: "1. e4 d5 2. exd5",
+		},
+		{
+			name:              "parses a promotion to Queen",
+			fen:               "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:                 "1. 57581",
+			expectedAlgebraic: "1. e8=Q",
+		},
+		{
+			name:              "parses an underpromotion to Knight",
+			fen:               "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:                 "1. 57584",
+			expectedAlgebraic: "1. e8=N",
+		},
+		{
+			name:       "fails on an illegal move",
+			fen:        defaultFEN,
+			s:          "1. 5255",
+			expectsErr: true,
+		},
+		{
+			name:       "fails on 1818, rather than matching an action that doesn't move pieces",
+			fen:        defaultFEN,
+			s:          "1. 5254 1818",
+			expectsErr: true,
+		},
+		{
+			name:       "fails on a promotion without the promoted piece",
+			fen:        "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:          "1. 5758",
+			expectsErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserICCF(characteristics{}).parse(g, tc.s)
+			if tc.expectsErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expectedGameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.expectedAlgebraic)
+			require.NoError(t, err)
+			require.Len(t, gameSteps, len(expectedGameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, expectedGameSteps[i].a, gameSteps[i].a)
+			}
+		})
+	}
+}
+
+func TestEmitICCFRoundTrip(t *testing.T) {
+	testCases := []struct {
+		fen   string
+		match string
+	}{
+		{
+			fen:   defaultFEN,
+			match: "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7 11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5 Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2",
+		},
+		{
+			fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			match: "1. O-O-O O-O 2. d6 hxg2 3. dxc7 gxh1=Q 4. c8=N",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("match %v", i), func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.match)
+			require.NoError(t, err)
+			iccf := ""
+			for i, gs := range emitSteps(emitICCF, g, gameSteps) {
+				if i%2 == 0 {
+					iccf += fmt.Sprintf("%v. ", i/2+1)
+				}
+				iccf += gs.s + " "
+			}
+			actualGameSteps, err := newNotationParserICCF(characteristics{}).parse(g, iccf)
+			require.NoError(t, err, iccf)
+			require.Len(t, actualGameSteps, len(gameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, gameSteps[i].a, actualGameSteps[i].a)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"strings"
+)
+
+func newNotationParserLongAlgebraic(initialCharacteristics characteristics) *notationParser {
+	var (
+		transitions = withSeparatorTransitions(map[string]map[string]func([]string) tokenMatch{
+			"move": {
+				// Move or capture, e.g. `e2-e4`, `Nb8xd7+`, `e7-e8=Q` or `e5xd6 e.p.`
+				`([QKBNR]?)([a-h])([1-8])([-x:])([a-h])([1-8])(?:([=\(]?)([QBNR])\)?)?( ?e\.p\.)?(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+					sFromPieceType, fromSquareFile, fromSquareRank, captureSymbol, toSquareFile, toSquareRank, promotionSymbol, sPromotionPieceType, enPassantCapture, threatenSymbol, _ := ms[1], ms[2], ms[3], ms[4], ms[5], ms[6], ms[7], ms[8], ms[9], ms[10], ms[11]
+					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+					isCapture := captureSymbol != "-"
+					// Without the optional `e.p.` suffix, a Pawn's capture may or may not be en passant
+					isEnPassantCapture := pBool(false)
+					if isCapture && sFromPieceType == "" {
+						isEnPassantCapture = nil
+					}
+					if strings.HasSuffix(enPassantCapture, "e.p.") {
+						isEnPassantCapture = pBool(true)
+					}
+					ap := actionPattern{
+						fromPieceType:      stringToPieceType(sFromPieceType),
+						fromX:              fileToPInt(fromSquareFile),
+						fromY:              rankToPInt(fromSquareRank),
+						toX:                fileToPInt(toSquareFile),
+						toY:                rankToPInt(toSquareRank),
+						isCapture:          pBool(isCapture),
+						isPromotion:        pBool(sPromotionPieceType != ""),
+						isCastle:           pBool(false),
+						isResign:           pBool(false),
+						isEnPassantCapture: isEnPassantCapture,
+						isCheck:            isCheck,
+						isCheckmate:        isCheckmate,
+					}
+					ch := characteristics{usesCheckSymbol: usesCheckSymbol, usesCheckmateSymbol: usesCheckmateSymbol}
+					if sPromotionPieceType != "" {
+						ap.promotionPieceType = stringToPieceType(sPromotionPieceType)
+						ch.usesPromotionSymbol = &promotionSymbol
+					}
+					return tokenMatch{ms[0], &ap, ch}
+				},
+
+				// Castling
+				`(0-0-0|0-0|O-O-O|O-O)(\+|†|ch|dbl\.? ?ch|\+\+|dis\.? ?ch|#|mate|‡|≠|X|x|×)?(!!|\?\?|!\?|\?!|!|\?)?`: func(ms []string) tokenMatch {
+					castlingSymbol, threatenSymbol, _ := ms[1], ms[2], ms[3]
+					isCheck, isCheckmate, usesCheckSymbol, usesCheckmateSymbol := processThreatenSymbol(threatenSymbol)
+					ap := actionPattern{
+						isCastle:           pBool(true),
+						isQueensideCastle:  pBool(castlingSymbol == "0-0-0" || castlingSymbol == "O-O-O"),
+						isKingsideCastle:   pBool(castlingSymbol == "0-0" || castlingSymbol == "O-O"),
+						isCapture:          pBool(false),
+						isPromotion:        pBool(false),
+						isResign:           pBool(false),
+						isEnPassantCapture: pBool(false),
+						isCheck:            isCheck,
+						isCheckmate:        isCheckmate,
+					}
+					cs := string(castlingSymbol[0])
+					ch := characteristics{
+						usesCheckSymbol:     usesCheckSymbol,
+						usesCheckmateSymbol: usesCheckmateSymbol,
+						usesCastlingSymbol:  &cs,
+					}
+					return tokenMatch{ms[0], &ap, ch}
+				},
+			},
+		})
+	)
+
+	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationParserLongAlgebraic(t *testing.T) {
+	testCases := []struct {
+		name              string
+		fen               string
+		s                 string
+		expectedAlgebraic string
+		expectsErr        bool
+	}{
+		{
+			name:              "parses moves, captures and castling",
+			fen:               defaultFEN,
+			s:                 "1. e2-e4 d7-d5 2. e4xd5 Qd8xd5 3. Nb1-c3 Qd5-a5 4. Ng1-f3 Bc8-g4 5. Bf1-e2 Nb8-c6 6. O-O O-O-O",
+			expectedAlgebraic: "1. e4 d5 2. exd5 Qxd5 3. Nc3 Qa5 4. Nf3 Bg4 5. Be2 Nc6 6. O-O O-O-O",
+		},
+		{
+			name:              "parses en passant with its suffix",
+			fen:               "4k3/3p1p2/8/4P3/8/8/8/4K3 w - - 0 1",
+			s:                 "1. Ke1-e2 d7-d5 2. e5xd6 e.p.",
+			expectedAlgebraic: "1. Ke2 d5 2. exd6 e.p.",
+		},
+		{
+			name:              "parses en passant without its suffix",
+			fen:               "4k3/3p1p2/8/4P3/8/8/8/4K3 w - - 0 1",
+			s:                 "1. Ke1-e2 f7-f5 2. e5xf6",
+			expectedAlgebraic: "1. Ke2 f5 2. exf6",
+		},
+		{
+			name:              "parses promotions with checks",
+			fen:               "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:                 "1. e7-e8=Q+",
+			expectedAlgebraic: "1. e8=Q+",
+		},
+		{
+			name:       "fails on a move written as a capture",
+			fen:        defaultFEN,
+			s:          "1. e2xe4",
+			expectsErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserLongAlgebraic(characteristics{}).parse(g, tc.s)
+			if tc.expectsErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expectedGameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.expectedAlgebraic)
+			require.NoError(t, err)
+			require.Len(t, gameSteps, len(expectedGameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, expectedGameSteps[i].a, gameSteps[i].a)
+			}
+		})
+	}
+}
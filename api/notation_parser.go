@@ -4,20 +4,186 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 var (
-	errInvalidRegexp       = errors.New("invalid regexp; this should not happen")
-	errDidntMatchAnyRegexp = errors.New("invalid state at step TODO: didn't match any regexp")
+	errInvalidRegexp        = errors.New("invalid regexp; this should not happen")
+	errUnknownInputNotation = errors.New("unknown notation: please use one of {algebraic|lan|uci|descriptive|iccf|auto} or empty string")
+	errNoActionsInNotation  = errors.New("didn't match any action")
 )
 
+// NotationError is an error found while parsing a notation string. It describes
+// where the problem is, both as a byte offset and as a line and column, relative
+// to the beginning of the notation string, and which action it happened on.
+// Lines, columns and plies start at 1.
+//
+// - `column` is counted in characters (i.e. Unicode code points), so that e.g.
+// `♘` or `К` count as one, and `columnUTF16` in UTF-16 code units, like
+// JavaScript strings and most editors count them.
+//
+// - `token` is the offending part of the notation string, e.g. `Qxf7#`.
+//
+// - `ply` is the number of the action of the notation string that couldn't be
+// parsed, e.g. 7 for White's fourth action.
+//
+// - `expected` is the class of token that was expected at that point, one of
+// `{full move number|action|half move separator|full move separator}`.
+//
+// - `alternatives` are the legal actions at that point in Standard Algebraic
+// Notation, if an action was expected.
+type NotationError struct {
+	Offset       int      `json:"offset"`
+	Line         int      `json:"line"`
+	Column       int      `json:"column"`
+	ColumnUTF16  int      `json:"columnUTF16"`
+	Token        string   `json:"token"`
+	Ply          int      `json:"ply"`
+	Expected     string   `json:"expected"`
+	Alternatives []string `json:"alternatives,omitempty"`
+	Message      string   `json:"error"`
+}
+
+func (e NotationError) Error() string {
+	return fmt.Sprintf("at line %v, column %v (offset %v), ply %v [%v]: %v", e.Line, e.Column, e.Offset, e.Ply, e.Token, e.Message)
+}
+
+// notationTokenClasses are the human-readable names of the parser's steps, for NotationError's `expected`.
+var notationTokenClasses = map[string]string{
+	"full_move_start":     "full move number",
+	"move":                "action",
+	"half_move_separator": "half move separator",
+	"full_move_separator": "full move separator",
+}
+
+// newNotationError describes an error found at the given offset of the notation string, while parsing the given step
+// starting from the game g. An empty token means that the offending token is the word at the offset.
+func (p *notationParser) newNotationError(offset int, step string, g game, token string, err error) NotationError {
+	if token == "" {
+		token = wordAt(p.s[offset:])
+	}
+	column, columnUTF16 := columnsAt(p.s, offset)
+	ne := NotationError{
+		Offset:      offset,
+		Line:        1 + strings.Count(p.s[:offset], "\n"),
+		Column:      column,
+		ColumnUTF16: columnUTF16,
+		Token:       token,
+		Ply:         len(p.stepParser.alternatives[0].gameSteps) + 1,
+		Expected:    notationTokenClasses[step],
+		Message:     err.Error(),
+	}
+	if step == "move" {
+		ne.Alternatives = legalSANs(g)
+	}
+	return ne
+}
+
+// columnsAt returns the column of the given byte offset of s, within its line, counted in characters and in UTF-16
+// code units.
+func columnsAt(s string, offset int) (int, int) {
+	column, columnUTF16 := 1, 1
+	for _, r := range s[strings.LastIndexByte(s[:offset], '\n')+1 : offset] {
+		column++
+		columnUTF16 += len(utf16.Encode([]rune{r}))
+	}
+	return column, columnUTF16
+}
+
+// wordAt returns the beginning of s up to the first whitespace, or its first character if it starts with whitespace.
+func wordAt(s string) string {
+	switch i := strings.IndexAny(s, " \t\r\n"); {
+	case i > 0:
+		return s[:i]
+	case i == -1:
+		return s
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
+
+// legalSANs returns every legal move of the game in Standard Algebraic Notation, in the order of the game's actions.
+func legalSANs(g game) []string {
+	var (
+		sans     = []string{}
+		suffixes = g.threatenSuffixes()
+	)
+	for i, a := range g.actions {
+		if a.isMove() {
+			sans = append(sans, algebraicMove(g, a, characteristics{})+suffixes[i])
+		}
+	}
+	return sans
+}
+
+// notationParsers build a parser for each notation that can be parsed, given the characteristics that the notation
+// string is known to have.
+var notationParsers = map[string]func(initialCharacteristics characteristics) *notationParser{
+	"algebraic":   newNotationParserAlgebraic,
+	"lan":         newNotationParserLongAlgebraic,
+	"uci":         newNotationParserUCI,
+	"descriptive": newNotationParserDescriptive,
+	"iccf":        newNotationParserICCF,
+}
+
+// autoNotationOrder is the order in which the "auto" notation tries the parsers; the first one to succeed wins.
+var autoNotationOrder = []string{"algebraic", "lan", "uci", "descriptive", "iccf"}
+
+// parseNotation parses the notation string with the parser of the given notation, seeded with the given
+// characteristics, and returns the notation that was used and the characteristics learnt while parsing. An empty
+// notation is Algebraic Notation.
+//
+// The "auto" notation tries every parser. A parser that doesn't parse any actions from a notation string that isn't
+// blank doesn't succeed. If none succeeds, the result of the one that parsed the most actions is
+// returned, as it's probably the notation that was meant.
+func parseNotation(initialGame game, s string, notation string, initialCharacteristics characteristics) (string, []gameStep, characteristics, error) {
+	if notation == "" {
+		notation = "algebraic"
+	}
+	if notation != "auto" {
+		newParser, ok := notationParsers[notation]
+		if !ok {
+			return notation, nil, characteristics{}, errUnknownInputNotation
+		}
+		p := newParser(initialCharacteristics)
+		gameSteps, err := p.parse(initialGame, s)
+		return notation, gameSteps, p.characteristics, err
+	}
+
+	var (
+		bestNotation  string
+		bestGameSteps []gameStep
+		bestParser    *notationParser
+		bestErr       error
+	)
+	for _, candidate := range autoNotationOrder {
+		p := notationParsers[candidate](initialCharacteristics)
+		gameSteps, err := p.parse(initialGame, s)
+		if err == nil && len(gameSteps) == 0 && strings.TrimSpace(s) != "" {
+			// e.g. `7163` is a full move number in most notations, but that's hardly what was meant
+			err = p.newNotationError(0, "move", initialGame, "", errNoActionsInNotation)
+		}
+		if err == nil {
+			return candidate, gameSteps, p.characteristics, nil
+		}
+		if bestParser == nil || len(gameSteps) > len(bestGameSteps) {
+			bestNotation, bestGameSteps, bestParser, bestErr = candidate, gameSteps, p, err
+		}
+	}
+	return bestNotation, bestGameSteps, bestParser.characteristics, bestErr
+}
+
 type actionPattern struct {
 	fromPieceType      pieceType
 	fromX              *int
 	fromY              *int
 	toX                *int
 	toY                *int
+	isMove             *bool
 	isCapture          *bool
 	isResign           *bool
 	isPromotion        *bool
@@ -32,6 +198,13 @@ type actionPattern struct {
 	capturedPieceY     *int
 	isCheck            *bool
 	isCheckmate        *bool
+
+	// For notations where squares may be ambiguous or relative to the player (e.g. Descriptive Notation): sets of
+	// files, and a rank from 1 to 8 counted from the side of the player whose piece moves
+	fromXs          []int
+	toXs            []int
+	toRank          *int
+	capturedPieceXs []int
 }
 
 func (p actionPattern) String() string {
@@ -52,6 +225,9 @@ func (p actionPattern) String() string {
 	if p.toY != nil {
 		sb.WriteString(fmt.Sprintf("{a.toXY.y}:%v\n", *p.toY))
 	}
+	if p.isMove != nil {
+		sb.WriteString(fmt.Sprintf("{a.isMove()}:%v\n", *p.isMove))
+	}
 	if p.isCapture != nil {
 		sb.WriteString(fmt.Sprintf("{a.isCapture}:%v\n", *p.isCapture))
 	}
@@ -88,6 +264,18 @@ func (p actionPattern) String() string {
 	if p.capturedPieceY != nil {
 		sb.WriteString(fmt.Sprintf("{a.capturedPiece.xy.y}:%v\n", *p.capturedPieceY))
 	}
+	if p.fromXs != nil {
+		sb.WriteString(fmt.Sprintf("{a.fromPiece.xy.x in}:%v\n", p.fromXs))
+	}
+	if p.toXs != nil {
+		sb.WriteString(fmt.Sprintf("{a.toXY.x in}:%v\n", p.toXs))
+	}
+	if p.toRank != nil {
+		sb.WriteString(fmt.Sprintf("{a.toXY rank for owner}:%v\n", *p.toRank))
+	}
+	if p.capturedPieceXs != nil {
+		sb.WriteString(fmt.Sprintf("{a.capturedPiece.xy.x in}:%v\n", p.capturedPieceXs))
+	}
 	return sb.String()
 }
 
@@ -97,6 +285,7 @@ func (p *actionPattern) isMatch(a action) bool {
 		!intMatcher(p.fromY)(a.fromPiece.xy.y) ||
 		!intMatcher(p.toX)(a.toXY.x) ||
 		!intMatcher(p.toY)(a.toXY.y) ||
+		!boolMatcher(p.isMove)(a.isMove()) ||
 		!boolMatcher(p.isCapture)(a.isCapture) ||
 		!boolMatcher(p.isResign)(a.isResign) ||
 		!boolMatcher(p.isPromotion)(a.isPromotion) ||
@@ -108,12 +297,34 @@ func (p *actionPattern) isMatch(a action) bool {
 		!pieceTypeMatcher(p.promotionPieceType)(a.promotionPieceType) ||
 		!pieceTypeMatcher(p.capturedPieceType)(a.capturedPiece.pieceType) ||
 		!intMatcher(p.capturedPieceX)(a.capturedPiece.xy.x) ||
-		!intMatcher(p.capturedPieceY)(a.capturedPiece.xy.y) {
+		!intMatcher(p.capturedPieceY)(a.capturedPiece.xy.y) ||
+		!intsMatcher(p.fromXs)(a.fromPiece.xy.x) ||
+		!intsMatcher(p.toXs)(a.toXY.x) ||
+		!rankMatcher(p.toRank, a.fromPiece.owner)(a.toXY.y) ||
+		!intsMatcher(p.capturedPieceXs)(a.capturedPiece.xy.x) {
 		return false
 	}
 	return true
 }
 
+// samePieceAndDestination returns a pattern that only keeps the piece type and the destination of this pattern. For
+// castling, the piece type is the King's.
+func (p actionPattern) samePieceAndDestination() actionPattern {
+	relaxed := actionPattern{fromPieceType: p.fromPieceType, toX: p.toX, toY: p.toY, toXs: p.toXs, toRank: p.toRank}
+	if p.isCastle != nil && *p.isCastle {
+		relaxed.fromPieceType = pieceKing
+	}
+	return relaxed
+}
+
+// samePiece returns a pattern that only keeps the piece type of this pattern. For castling, the piece type is the
+// King's.
+func (p actionPattern) samePiece() actionPattern {
+	relaxed := p.samePieceAndDestination()
+	relaxed.toX, relaxed.toY, relaxed.toXs, relaxed.toRank = nil, nil, nil, nil
+	return relaxed
+}
+
 type characteristics struct {
 	isCheck                        bool
 	isCheckmate                    bool
@@ -128,6 +339,11 @@ type characteristics struct {
 	usesEndGameSymbol              *string
 	usesPromotionSymbol            *string
 	usesCastlingSymbol             *string
+
+	// The languages of the piece letters (see pieceLetterLanguages) that the notation is consistent with, or nil if
+	// unknown. Unlike the symbols, a letter may be consistent with many languages, e.g. `K` is the King in English and
+	// German, so the set narrows down as tokens are parsed.
+	usesPieceLetters []string
 }
 
 func boolMatcher(v *bool) func(interface{}) bool {
@@ -139,6 +355,33 @@ func intMatcher(v *int) func(interface{}) bool {
 func pieceTypeMatcher(v pieceType) func(interface{}) bool {
 	return func(w interface{}) bool { return v == pieceNone || v == w.(pieceType) }
 }
+func intsMatcher(vs []int) func(interface{}) bool {
+	return func(w interface{}) bool {
+		if vs == nil {
+			return true
+		}
+		for _, v := range vs {
+			if v == w.(int) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// rankMatcher matches a y coordinate against a rank from 1 to 8 counted from the side of the given player, so that
+// e.g. rank 1 is y=7 for White, but y=0 for Black.
+func rankMatcher(rank *int, owner color) func(interface{}) bool {
+	return func(w interface{}) bool {
+		if rank == nil {
+			return true
+		}
+		if owner == colorWhite {
+			return 8-*rank == w.(int)
+		}
+		return *rank-1 == w.(int)
+	}
+}
 
 type gameStep struct {
 	s string
@@ -150,13 +393,24 @@ func (s gameStep) clone() gameStep {
 	return gameStep{
 		s: s.s,
 		a: s.a,
-		g: s.g.clone(),
+		g: cloneWithActions(s.g),
 	}
 }
 
+// cloneWithActions clones the game, keeping its actions, which are needed by the steps that follow from it (e.g. to
+// disambiguate them) and also returned. N.B. actions are never modified in place, so it's safe to share them.
+func cloneWithActions(g game) game {
+	cloned := g.clone()
+	cloned.actions = g.actions
+	return cloned
+}
+
 type gameAlternative struct {
 	initialGame game
 	gameSteps   []gameStep
+
+	// The languages of the piece letters that the actions of this alternative were read in, or nil if unknown
+	pieceLetters []string
 }
 
 func (a gameAlternative) clone() gameAlternative {
@@ -165,8 +419,9 @@ func (a gameAlternative) clone() gameAlternative {
 		clonedGameSteps[i] = a.gameSteps[i].clone()
 	}
 	return gameAlternative{
-		initialGame: a.initialGame.clone(),
-		gameSteps:   clonedGameSteps,
+		initialGame:  cloneWithActions(a.initialGame),
+		gameSteps:    clonedGameSteps,
+		pieceLetters: a.pieceLetters,
 	}
 }
 
@@ -184,17 +439,49 @@ type gameStepParser struct {
 	possibleNextActions []action
 }
 
-func newGameStepParser(initialGame game) *gameStepParser {
+func newGameStepParser(initialGame game, pieceLetters []string) *gameStepParser {
 	return &gameStepParser{
-		alternatives: []gameAlternative{gameAlternative{initialGame: initialGame}},
+		alternatives: []gameAlternative{gameAlternative{initialGame: initialGame, pieceLetters: pieceLetters}},
 	}
 }
 
-func (p *gameStepParser) next(ap actionPattern, actionString string) bool {
-	newAlternatives := []gameAlternative{}
-	for _, alternative := range p.alternatives {
-		for _, action := range alternative.currentGame().actions {
-			if ap.isMatch(action) {
+// next advances the alternatives with every action that the readings of an action token match, and returns the
+// reading that was used. A token may have many readings, e.g. `Rd1` is a Rook's move in English, but a King's move
+// in Spanish; only the first reading that matches any action is used, along with the rest that read the same string.
+// Rather than choosing one reading, every one of them yields alternatives, which remember the languages of the
+// piece letters that they were read in, so that later tokens drop the alternatives that they contradict.
+func (p *gameStepParser) next(readings []tokenMatch) (tokenMatch, bool) {
+	type alternativeAction struct {
+		alternative int
+		action      action
+	}
+	var (
+		newAlternatives = []gameAlternative{}
+		indexes         = map[alternativeAction]int{} // Of newAlternatives, so that many readings of an action are one
+		matched         *tokenMatch
+	)
+	for _, reading := range readings {
+		if matched != nil && reading.match != matched.match {
+			continue
+		}
+		ap := *reading.ap
+		for i, alternative := range p.alternatives {
+			pieceLetters := alternative.pieceLetters
+			if pieceLetters == nil {
+				pieceLetters = reading.ch.usesPieceLetters
+			} else if reading.ch.usesPieceLetters != nil {
+				if pieceLetters = intersectStrings(pieceLetters, reading.ch.usesPieceLetters); len(pieceLetters) == 0 {
+					continue
+				}
+			}
+			for _, action := range alternative.currentGame().actions {
+				if !ap.isMatch(action) {
+					continue
+				}
+				if j, ok := indexes[alternativeAction{i, action}]; ok {
+					newAlternatives[j].pieceLetters = unionPieceLetters(newAlternatives[j].pieceLetters, pieceLetters)
+					continue
+				}
 				newGame := alternative.currentGame().doAction(action)
 				if ap.isCheck != nil && newGame.isCheck != *ap.isCheck {
 					continue
@@ -203,16 +490,17 @@ func (p *gameStepParser) next(ap actionPattern, actionString string) bool {
 					continue
 				}
 				newAlternative := alternative.clone()
-				newAlternative.gameSteps = append(newAlternative.gameSteps, gameStep{s: actionString, a: action, g: newGame})
+				newAlternative.pieceLetters = pieceLetters
+				newAlternative.gameSteps = append(newAlternative.gameSteps, gameStep{s: reading.match, a: action, g: newGame})
+				indexes[alternativeAction{i, action}] = len(newAlternatives)
 				newAlternatives = append(newAlternatives, newAlternative)
+				if matched == nil {
+					reading := reading
+					matched = &reading
+				}
 			}
 		}
 	}
-	// fmt.Printf("Alternatives for %v: %v\n", actionString, len(newAlternatives))
-	// for _, a := range newAlternatives {
-	// 	fmt.Println(a.currentGame())
-	// 	fmt.Println()
-	// }
 
 	if len(newAlternatives) == 0 {
 		actionSet := map[action]struct{}{}
@@ -227,13 +515,22 @@ func (p *gameStepParser) next(ap actionPattern, actionString string) bool {
 		}
 		p.isSuccess = false
 		p.parsedGame = p.alternatives[0]
-		return false
+		return tokenMatch{}, false
 	}
 	p.isSuccess = true
 	p.alternatives = newAlternatives
 	p.parsedGame = p.alternatives[0]
 	p.possibleNextActions = []action{}
-	return true
+	return *matched, true
+}
+
+// pieceLetters returns the languages of the piece letters that any alternative was read in, or nil if unknown.
+func (p *gameStepParser) pieceLetters() []string {
+	pieceLetters := p.alternatives[0].pieceLetters
+	for _, alternative := range p.alternatives[1:] {
+		pieceLetters = unionPieceLetters(pieceLetters, alternative.pieceLetters)
+	}
+	return pieceLetters
 }
 
 type tokenMatch struct {
@@ -245,10 +542,15 @@ type tokenMatch struct {
 type notationParser struct {
 	s          string
 	stepParser *gameStepParser
+	rxs        map[string]*regexp.Regexp
 
 	transitions           map[string]map[string]func([]string) tokenMatch
 	evolveCharacteristics func(ch characteristics, sc characteristics) (characteristics, error)
 	characteristics       characteristics
+
+	// The initial characteristics are enforced more strictly than the learnt ones: e.g. if the check symbol is known
+	// beforehand, checks without it are errors
+	enforced characteristics
 }
 
 func newNotationParser(
@@ -259,25 +561,85 @@ func newNotationParser(
 		transitions:           transitions,
 		evolveCharacteristics: evolveCharacteristics,
 		characteristics:       initialCharacteristics,
+		enforced:              initialCharacteristics,
 	}
 }
 
-func (p *notationParser) parse(initialGame game, s string) ([]gameStep, error) {
-	p.stepParser = newGameStepParser(initialGame)
-	p.s = s
-
-	// Compile all regexes
-	rxs := map[string]*regexp.Regexp{}
+func (p *notationParser) compileRegexps() map[string]*regexp.Regexp {
+	if p.rxs != nil {
+		return p.rxs
+	}
+	p.rxs = map[string]*regexp.Regexp{}
 	for step := range p.transitions {
 		for srx := range p.transitions[step] {
-			rxs[srx] = regexp.MustCompile(fmt.Sprintf("^%v", srx))
+			p.rxs[srx] = regexp.MustCompile(fmt.Sprintf("^%v", srx))
+		}
+	}
+	return p.rxs
+}
+
+// parseAction resolves a single, already sliced action token (e.g. `Nbd7+`) against the given game, using the
+// "move" transitions of the parser. The whole token must be matched, and only by one action. Notation characteristics
+// are not evolved.
+func (p *notationParser) parseAction(g game, token string) (gameStep, error) {
+	gss, _ := p.matchActions(g, token)
+	switch {
+	case len(gss) == 0:
+		return gameStep{}, fmt.Errorf("token %v didn't match any valid action", token)
+	case len(gss) > 1:
+		return gameStep{}, fmt.Errorf("%w: %v could be any of %v", errAmbiguousSAN, token, emitSANs(g, gss))
+	}
+	return gss[0], nil
+}
+
+// matchActions returns a step for every one of the game's actions that the given action token matches, in the order
+// of the game's actions, and the patterns of the "move" transitions that matched the whole token syntactically. More
+// than one step means that the token is ambiguous, and none with some patterns means that it's illegal.
+func (p *notationParser) matchActions(g game, token string) ([]gameStep, []actionPattern) {
+	rxs := p.compileRegexps()
+	var aps []actionPattern
+	for rx, fs := range p.transitions["move"] {
+		matches := rxs[rx].FindStringSubmatch(token)
+		if matches == nil || matches[0] != token {
+			continue
+		}
+		tm := fs(matches)
+		if _, err := p.evolveCharacteristics(p.characteristics, tm.ch); err != nil {
+			continue
+		}
+		aps = append(aps, *tm.ap)
+	}
+
+	var gss []gameStep
+	for _, a := range g.actions {
+		for _, ap := range aps {
+			if !ap.isMatch(a) {
+				continue
+			}
+			newGame := g.doAction(a)
+			if (ap.isCheck != nil && newGame.isCheck != *ap.isCheck) || (ap.isCheckmate != nil && newGame.isCheckmate != *ap.isCheckmate) {
+				continue
+			}
+			gss = append(gss, gameStep{s: token, a: a, g: newGame})
+			break
 		}
 	}
+	return gss, aps
+}
+
+func (p *notationParser) parse(initialGame game, s string) ([]gameStep, error) {
+	p.stepParser = newGameStepParser(initialGame, p.characteristics.usesPieceLetters)
+	p.s = s
+
+	rxs := p.compileRegexps()
 
 	stepOrder := []string{"full_move_start", "move", "half_move_separator", "move", "full_move_separator"}
 	stepI := 0
 	i := 0
 	for i < len(p.s) {
+		// The game before this token, to describe errors
+		g := p.stepParser.alternatives[0].currentGame()
+
 		// Calculate all tokens that match
 		var tokenMatches []tokenMatch
 		for rx, fs := range p.transitions[stepOrder[stepI]] {
@@ -289,25 +651,43 @@ func (p *notationParser) parse(initialGame game, s string) ([]gameStep, error) {
 
 		// Bail if no token matches
 		if len(tokenMatches) == 0 {
-			err := fmt.Errorf("at index %v [%v] didn't match any token", i, p.s[i:])
-			return p.stepParser.parsedGame.gameSteps, err
+			err := fmt.Errorf("didn't match any %v", notationTokenClasses[stepOrder[stepI]])
+			return p.stepParser.parsedGame.gameSteps, p.newNotationError(i, stepOrder[stepI], g, "", err)
 		}
 
 		tokenMatch := tokenMatches[0]
 
 		// Move steps will advance the game
 		if stepOrder[stepI] == "move" {
-			var ok bool
+			pieceLettersPrecedence(tokenMatches)
+			var (
+				readings          = tokenMatches[:0]
+				characteristicErr error
+			)
 			for _, tm := range tokenMatches {
-				if ok = p.stepParser.next(*tm.ap, tm.match); ok {
-					tokenMatch = tm
-					break // Many regexes may match the token, but only one should match any actions
+				// Readings that contradict the characteristics learnt so far (e.g. `Rd1` as a King's move in Spanish
+				// when the notation has already used German letters) are not considered
+				if _, err := p.evolveCharacteristics(p.characteristics, tm.ch); err != nil {
+					characteristicErr = err
+					continue
 				}
+				readings = append(readings, tm)
 			}
+			if len(readings) == 0 {
+				return p.stepParser.parsedGame.gameSteps, p.newNotationError(i, stepOrder[stepI], g, tokenMatch.match, characteristicErr)
+			}
+			// The parser before this token, to roll it back if the action breaks the enforced characteristics
+			stepParser := *p.stepParser
+			matched, ok := p.stepParser.next(readings)
 			if !ok {
-				err := fmt.Errorf("at %v matched token %v but no valid action found for it; options were: %v", i, tokenMatch.match, p.stepParser.possibleNextActions)
-				return p.stepParser.parsedGame.gameSteps, err
+				err := errors.New("doesn't match any legal action")
+				return p.stepParser.parsedGame.gameSteps, p.newNotationError(i, stepOrder[stepI], g, readings[0].match, err)
+			}
+			if err := p.enforceThreatenSymbols(matched); err != nil {
+				*p.stepParser = stepParser
+				return p.stepParser.parsedGame.gameSteps, p.newNotationError(i, stepOrder[stepI], g, matched.match, err)
 			}
+			tokenMatch = matched
 		}
 
 		// Calculate characteristics of the notation as we go through the string.
@@ -316,9 +696,21 @@ func (p *notationParser) parse(initialGame game, s string) ([]gameStep, error) {
 		// 2. A custom parser can already set that castling has to be e.g. O-O, so that if we find 0-0 that's an error.
 		newCharacteristics, err := p.evolveCharacteristics(p.characteristics, tokenMatch.ch)
 		if err != nil {
-			return p.stepParser.parsedGame.gameSteps, err
+			// Full move numbers are matched along with the whitespace around them, e.g. `1 `, which isn't part of the
+			// offending token unless it's all there is
+			token := tokenMatch.match
+			if trimmed := strings.TrimSpace(token); trimmed != "" {
+				token = trimmed
+			}
+			offset := i + strings.Index(tokenMatch.match, token)
+			return p.stepParser.parsedGame.gameSteps, p.newNotationError(offset, stepOrder[stepI], g, token, err)
 		}
 		p.characteristics = newCharacteristics
+		if stepOrder[stepI] == "move" {
+			// The alternatives know better which languages the piece letters may be in, as a token's reading only knows
+			// about itself
+			p.characteristics.usesPieceLetters = p.stepParser.pieceLetters()
+		}
 
 		// Advance the parser to the next token
 		i += len(tokenMatch.match)
@@ -331,3 +723,186 @@ func (p *notationParser) parse(initialGame game, s string) ([]gameStep, error) {
 	}
 	return p.stepParser.parsedGame.gameSteps, nil
 }
+
+// enforceThreatenSymbols fails if the last action checks or checkmates without the enforced symbol, e.g. `Qxf7` when
+// checkmates must be suffixed with `#`.
+func (p *notationParser) enforceThreatenSymbols(tm tokenMatch) error {
+	g := p.stepParser.parsedGame.currentGame()
+	switch {
+	case g.isCheckmate && p.enforced.usesCheckmateSymbol != nil && tm.ch.usesCheckmateSymbol == nil:
+		return fmt.Errorf("expecting CheckmateSymbol %v but found none", *p.enforced.usesCheckmateSymbol)
+	case g.isCheck && !g.isCheckmate && p.enforced.usesCheckSymbol != nil && tm.ch.usesCheckSymbol == nil:
+		return fmt.Errorf("expecting CheckSymbol %v but found none", *p.enforced.usesCheckSymbol)
+	}
+	return nil
+}
+
+// withSeparatorTransitions adds the transitions shared by all notation parsers to the given ones, i.e. the ones that
+// match the full move numbers and the whitespace between actions.
+func withSeparatorTransitions(transitions map[string]map[string]func([]string) tokenMatch) map[string]map[string]func([]string) tokenMatch {
+	separatorTransitions := map[string]map[string]func([]string) tokenMatch{
+		"full_move_start": {
+			`[\t\f\r ]*([0-9]+)?(\.)?[\t\f\r ]*`: func(ms []string) tokenMatch {
+				return fullMoveStartTokenMatch(ms[0], ms[1], ms[2])
+			},
+		},
+		"half_move_separator": {
+			`[\t\f\r ]+`: func(ms []string) tokenMatch {
+				return tokenMatch{ms[0], nil, characteristics{}}
+			},
+		},
+		"full_move_separator": {
+			`([\t\f\r ]*?\n|[\t\f\r ]+)`: func(ms []string) tokenMatch {
+				usesNewlineAsFullMoveSeparator := pBool(strings.Contains(ms[0], "\n"))
+				return tokenMatch{ms[0], nil, characteristics{usesNewlineAsFullMoveSeparator: usesNewlineAsFullMoveSeparator}}
+			},
+		},
+	}
+	for step, stepTransitions := range separatorTransitions {
+		transitions[step] = stepTransitions
+	}
+	return transitions
+}
+
+// fullMoveStartTokenMatch is the token match of a full move start, given the full move number and dot that it has,
+// which may be empty.
+func fullMoveStartTokenMatch(match string, number string, dot string) tokenMatch {
+	var fullMoveNumber *int
+	if len(number) > 0 {
+		fmn, _ := strconv.Atoi(number)
+		fullMoveNumber = &fmn
+	}
+	var usesFullMoveDot *bool
+	if len(number) > 0 {
+		usesFullMoveDot = pBool(len(dot) == 1)
+	}
+	return tokenMatch{match, nil, characteristics{fullMoveNumber: fullMoveNumber, usesFullMoveDot: usesFullMoveDot}}
+}
+
+// evolveCharacteristics evolves the characteristics learnt so far with the ones of the last token, and is shared by all
+// notation parsers: characteristics that weren't known become known, and contradicting the known ones is an error.
+//
+// TODO human-readable error messages here. Also, lacking some context.
+func evolveCharacteristics(ch characteristics, sc characteristics) (characteristics, error) {
+	if sc.usesCheckSymbol != nil {
+		if ch.usesCheckSymbol == nil {
+			ch.usesCheckSymbol = sc.usesCheckSymbol
+		} else if *ch.usesCheckSymbol != *sc.usesCheckSymbol {
+			return ch, fmt.Errorf("expecting CheckSymbol %v but found %v", *ch.usesCheckSymbol, *sc.usesCheckSymbol)
+		}
+	}
+	if sc.usesCheckmateSymbol != nil {
+		if ch.usesCheckmateSymbol == nil {
+			ch.usesCheckmateSymbol = sc.usesCheckmateSymbol
+		} else if *ch.usesCheckmateSymbol != *sc.usesCheckmateSymbol {
+			return ch, fmt.Errorf("expecting CheckmateSymbol %v but found %v", *ch.usesCheckmateSymbol, *sc.usesCheckmateSymbol)
+		}
+	}
+	if sc.usesFullMoveDot != nil {
+		if ch.usesFullMoveDot == nil {
+			ch.usesFullMoveDot = sc.usesFullMoveDot
+		} else if *ch.usesFullMoveDot != *sc.usesFullMoveDot {
+			return ch, fmt.Errorf("expecting FullMoveDot %v but found %v", *ch.usesFullMoveDot, *sc.usesFullMoveDot)
+		}
+	}
+	if sc.usesNewlineAsFullMoveSeparator != nil {
+		if ch.usesNewlineAsFullMoveSeparator == nil {
+			ch.usesNewlineAsFullMoveSeparator = sc.usesNewlineAsFullMoveSeparator
+		} else if *ch.usesNewlineAsFullMoveSeparator != *sc.usesNewlineAsFullMoveSeparator {
+			return ch, fmt.Errorf("expecting NewlineAsFullMoveSeparator %v but found %v", *ch.usesNewlineAsFullMoveSeparator, *sc.usesNewlineAsFullMoveSeparator)
+		}
+	}
+	if sc.usesThreatenSymbol != nil {
+		if ch.usesThreatenSymbol == nil {
+			ch.usesThreatenSymbol = sc.usesThreatenSymbol
+		} else if *ch.usesThreatenSymbol != *sc.usesThreatenSymbol {
+			return ch, fmt.Errorf("expecting ThreatenSymbol %v but found %v", *ch.usesThreatenSymbol, *sc.usesThreatenSymbol)
+		}
+	}
+	if sc.usesCaptureSymbol != nil {
+		if ch.usesCaptureSymbol == nil {
+			ch.usesCaptureSymbol = sc.usesCaptureSymbol
+		} else if *ch.usesCaptureSymbol != *sc.usesCaptureSymbol {
+			return ch, fmt.Errorf("expecting CaptureSymbol %v but found %v", *ch.usesCaptureSymbol, *sc.usesCaptureSymbol)
+		}
+	}
+	if sc.usesEndGameSymbol != nil {
+		if ch.usesEndGameSymbol == nil {
+			ch.usesEndGameSymbol = sc.usesEndGameSymbol
+		} else if *ch.usesEndGameSymbol != *sc.usesEndGameSymbol {
+			return ch, fmt.Errorf("expecting EndGameSymbol %v but found %v", *ch.usesEndGameSymbol, *sc.usesEndGameSymbol)
+		}
+	}
+	if sc.usesPromotionSymbol != nil {
+		if ch.usesPromotionSymbol == nil {
+			ch.usesPromotionSymbol = sc.usesPromotionSymbol
+		} else if *ch.usesPromotionSymbol != *sc.usesPromotionSymbol {
+			return ch, fmt.Errorf("expecting PromotionSymbol %v but found %v", *ch.usesPromotionSymbol, *sc.usesPromotionSymbol)
+		}
+	}
+	if sc.usesCastlingSymbol != nil {
+		if ch.usesCastlingSymbol == nil {
+			ch.usesCastlingSymbol = sc.usesCastlingSymbol
+		} else if *ch.usesCastlingSymbol != *sc.usesCastlingSymbol {
+			return ch, fmt.Errorf("expecting CastlingSymbol %v but found %v", *ch.usesCastlingSymbol, *sc.usesCastlingSymbol)
+		}
+	}
+	if sc.usesPieceLetters != nil {
+		if ch.usesPieceLetters == nil {
+			ch.usesPieceLetters = sc.usesPieceLetters
+		} else if common := intersectStrings(ch.usesPieceLetters, sc.usesPieceLetters); len(common) > 0 {
+			ch.usesPieceLetters = common
+		} else {
+			return ch, fmt.Errorf("expecting PieceLetters %v but found %v", ch.usesPieceLetters, sc.usesPieceLetters)
+		}
+	}
+	// TODO full move number
+	return ch, nil
+}
+
+// unionPieceLetters returns the languages of pieceLetterLanguages that are in either set. Nil means unknown, i.e. any
+// language, so the union with nil is nil.
+func unionPieceLetters(as, bs []string) []string {
+	if as == nil || bs == nil {
+		return nil
+	}
+	var union []string
+	for _, language := range pieceLetterLanguages {
+		if len(intersectStrings(as, []string{language})) > 0 || len(intersectStrings(bs, []string{language})) > 0 {
+			union = append(union, language)
+		}
+	}
+	return union
+}
+
+// intersectStrings returns the strings of as that are also in bs, in the order of as.
+func intersectStrings(as, bs []string) []string {
+	var common []string
+	for _, a := range as {
+		for _, b := range bs {
+			if a == b {
+				common = append(common, a)
+				break
+			}
+		}
+	}
+	return common
+}
+
+// pieceLettersPrecedence sorts token matches that read the same token in different languages in the order of
+// pieceLetterLanguages, so that alternatives are in a deterministic order, and the parsed game is the one read in the
+// first language when the whole notation string is consistent with many. Tokens without piece letters come first.
+func pieceLettersPrecedence(tms []tokenMatch) {
+	precedence := func(tm tokenMatch) int {
+		if len(tm.ch.usesPieceLetters) == 0 {
+			return -1
+		}
+		for i, language := range pieceLetterLanguages {
+			if language == tm.ch.usesPieceLetters[0] {
+				return i
+			}
+		}
+		return len(pieceLetterLanguages)
+	}
+	sort.SliceStable(tms, func(i, j int) bool { return precedence(tms[i]) < precedence(tms[j]) })
+}
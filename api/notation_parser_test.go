@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationError(t *testing.T) {
+	testCases := []struct {
+		name                string
+		notation            string
+		s                   string
+		expected            NotationError
+		expectedAlternative string
+	}{
+		{
+			name:     "describes a token that doesn't match any action on a later line",
+			notation: "algebraic",
+			s:        "1. e4 e5\n2. @",
+			expected: NotationError{
+				Offset:      12,
+				Line:        2,
+				Column:      4,
+				ColumnUTF16: 4,
+				Token:       "@",
+				Ply:         3,
+				Expected:    "action",
+				Message:     "didn't match any action",
+			},
+			expectedAlternative: "Nf3",
+		},
+		{
+			name:     "describes an illegal action",
+			notation: "iccf",
+			s:        "1. 5255",
+			expected: NotationError{
+				Offset:      3,
+				Line:        1,
+				Column:      4,
+				ColumnUTF16: 4,
+				Token:       "5255",
+				Ply:         1,
+				Expected:    "action",
+				Message:     "doesn't match any legal action",
+			},
+			expectedAlternative: "e4",
+		},
+		{
+			name:     "describes an action that is inconsistent with the style of the previous ones",
+			notation: "algebraic",
+			s:        "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O Nf6 5. d3 0-0",
+			expected: NotationError{
+				Offset:      48,
+				Line:        1,
+				Column:      49,
+				ColumnUTF16: 49,
+				Token:       "0-0",
+				Ply:         10,
+				Expected:    "action",
+				Message:     "expecting CastlingSymbol O but found 0",
+			},
+			expectedAlternative: "O-O",
+		},
+		{
+			name:     "counts columns in characters after figurines",
+			notation: "algebraic",
+			s:        "1. e4 e5 2. ♘f3 ♞c6 3. @",
+			expected: NotationError{
+				Offset:      27,
+				Line:        1,
+				Column:      24,
+				ColumnUTF16: 24,
+				Token:       "@",
+				Ply:         5,
+				Expected:    "action",
+				Message:     "didn't match any action",
+			},
+			expectedAlternative: "Bc4",
+		},
+		{
+			name:     "counts columns in characters after Cyrillic piece letters",
+			notation: "algebraic",
+			s:        "1. e4 e5\n2. Кf3 @",
+			expected: NotationError{
+				Offset:      17,
+				Line:        2,
+				Column:      8,
+				ColumnUTF16: 8,
+				Token:       "@",
+				Ply:         4,
+				Expected:    "action",
+				Message:     "didn't match any action",
+			},
+			expectedAlternative: "Nc6",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(defaultFEN)
+			require.NoError(t, err)
+			_, _, _, err = parseNotation(g, tc.s, tc.notation, characteristics{})
+			var ne NotationError
+			require.True(t, errors.As(err, &ne), err)
+			assert.Contains(t, ne.Alternatives, tc.expectedAlternative)
+			ne.Alternatives = nil
+			assert.Equal(t, tc.expected, ne)
+		})
+	}
+}
+
+func TestColumnsAt(t *testing.T) {
+	testCases := []struct {
+		s                   string
+		offset              int
+		expectedColumn      int
+		expectedColumnUTF16 int
+	}{
+		{s: "1. e4", offset: 3, expectedColumn: 4, expectedColumnUTF16: 4},
+		{s: "1. e4\n2. Nf3", offset: 9, expectedColumn: 4, expectedColumnUTF16: 4},
+		{s: "1. ♘f3 @", offset: 9, expectedColumn: 8, expectedColumnUTF16: 8},
+		{s: "1. Кf3 @", offset: 8, expectedColumn: 8, expectedColumnUTF16: 8},
+		{s: "1. 𝄞 @", offset: 8, expectedColumn: 6, expectedColumnUTF16: 7}, // Beyond the Basic Multilingual Plane
+	}
+	for _, tc := range testCases {
+		column, columnUTF16 := columnsAt(tc.s, tc.offset)
+		assert.Equal(t, tc.expectedColumn, column, tc.s)
+		assert.Equal(t, tc.expectedColumnUTF16, columnUTF16, tc.s)
+	}
+}
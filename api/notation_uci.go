@@ -0,0 +1,30 @@
+package api
+
+func newNotationParserUCI(initialCharacteristics characteristics) *notationParser {
+	var (
+		transitions = withSeparatorTransitions(map[string]map[string]func([]string) tokenMatch{
+			"move": {
+				// Move, capture, castling or promotion, e.g. `e2e4`, `e1g1` or `e7e8q`
+				`([a-h])([1-8])([a-h])([1-8])([qrbn])?`: func(ms []string) tokenMatch {
+					fromSquareFile, fromSquareRank, toSquareFile, toSquareRank, sPromotionPieceType := ms[1], ms[2], ms[3], ms[4], ms[5]
+					// Actions that don't move pieces are on a8 too, so `a8a8` would match them
+					ap := actionPattern{
+						isMove:      pBool(true),
+						fromX:       fileToPInt(fromSquareFile),
+						fromY:       rankToPInt(fromSquareRank),
+						toX:         fileToPInt(toSquareFile),
+						toY:         rankToPInt(toSquareRank),
+						isPromotion: pBool(sPromotionPieceType != ""),
+						isResign:    pBool(false),
+					}
+					if sPromotionPieceType != "" {
+						ap.promotionPieceType = uciLetterToPromotionPieceType[sPromotionPieceType[0]]
+					}
+					return tokenMatch{ms[0], &ap, characteristics{}}
+				},
+			},
+		})
+	)
+
+	return newNotationParser(transitions, evolveCharacteristics, initialCharacteristics)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotationParserUCI(t *testing.T) {
+	testCases := []struct {
+		name              string
+		fen               string
+		s                 string
+		expectedAlgebraic string
+		expectsErr        bool
+	}{
+		{
+			name:              "parses moves, captures and castling as the King's move",
+			fen:               defaultFEN,
+			s:                 "e2e4 d7d5 e4d5 d8d5 b1c3 d5a5 g1f3 c8g4 f1e2 b8c6 e1g1 e8c8",
+			expectedAlgebraic: "1. e4 d5 2. exd5 Qxd5 3. Nc3 Qa5 4. Nf3 Bg4 5. Be2 Nc6 6. O-O O-O-O",
+		},
+		{
+			name:              "parses move numbers",
+			fen:               defaultFEN,
+			s:                 "1. e2e4 e7e5 2. g1f3",
+			expectedAlgebraic: "1. e4 e5 2. Nf3",
+		},
+		{
+			name:              "parses underpromotions",
+			fen:               "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:                 "e7e8n",
+			expectedAlgebraic: "1. e8=N",
+		},
+		{
+			name:       "fails on a promotion without the promoted piece",
+			fen:        "k7/4P3/1K6/8/8/8/8/8 w - - 0 1",
+			s:          "e7e8",
+			expectsErr: true,
+		},
+		{
+			name:       "fails on a8a8, rather than matching an action that doesn't move pieces",
+			fen:        defaultFEN,
+			s:          "e2e4 a8a8",
+			expectsErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserUCI(characteristics{}).parse(g, tc.s)
+			if tc.expectsErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expectedGameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.expectedAlgebraic)
+			require.NoError(t, err)
+			require.Len(t, gameSteps, len(expectedGameSteps))
+			for i := range gameSteps {
+				assert.Equal(t, expectedGameSteps[i].a, gameSteps[i].a)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var errUnknownOutputGameField = errors.New("unknown OutputGame field: please use the JSON names of its fields, e.g. fenString")
+
+// outputGameFields is a set of OutputGame fields, by their JSON names. A nil set means all fields.
+type outputGameFields map[string]bool
+
+func (f outputGameFields) has(name string) bool {
+	return f == nil || f[name]
+}
+
+// outputGameFieldIndexes are the indexes of the OutputGame struct fields, by their JSON names.
+var outputGameFieldIndexes = func() map[string]int {
+	indexes := map[string]int{}
+	t := reflect.TypeOf(OutputGame{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonFieldName(t.Field(i)); name != "" {
+			indexes[name] = i
+		}
+	}
+	return indexes
+}()
+
+func jsonFieldName(f reflect.StructField) string {
+	if f.PkgPath != "" { // Unexported
+		return ""
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// hasActions returns true if either list of actions is selected, as both are calculated at once.
+func (f outputGameFields) hasActions() bool {
+	return f.has("actions") || f.has("nonMoveActions")
+}
+
+func newOutputGameFields(names []string) (outputGameFields, error) {
+	fields := outputGameFields{}
+	for _, name := range names {
+		if _, ok := outputGameFieldIndexes[name]; !ok {
+			return nil, errUnknownOutputGameField
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// IsFieldSelected returns true if the field with the given JSON name (e.g. `fenString`) was calculated, i.e. if
+// either all fields were selected, which is the default, or it was selected explicitly with the
+// WithOutputGameFields or WithStepGameFields options.
+func (o OutputGame) IsFieldSelected(name string) bool {
+	_, ok := outputGameFieldIndexes[name]
+	return ok && o.fields.has(name)
+}
+
+// MarshalJSON serialises only the selected fields, or all of them by default.
+func (o OutputGame) MarshalJSON() ([]byte, error) {
+	type outputGame OutputGame // Same fields, without this method
+	if o.fields == nil {
+		return json.Marshal(outputGame(o))
+	}
+
+	var (
+		buf bytes.Buffer
+		v   = reflect.ValueOf(o)
+		t   = v.Type()
+	)
+	buf.WriteByte('{')
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+		if name == "" || !o.fields[name] {
+			continue
+		}
+		if strings.HasSuffix(t.Field(i).Tag.Get("json"), ",omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+		value, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
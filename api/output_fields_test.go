@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputGameFields(t *testing.T) {
+	a := New(WithOutputGameFields("fenString", "isCheckmate", "actions"))
+
+	// Fool's mate
+	outputGame, err := a.ParseGame(InputGame{FENString: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"})
+	require.NoError(t, err)
+	byts, err := json.Marshal(outputGame)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fenString":"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3","actions":[],"isCheckmate":true}`, string(byts))
+	assert.True(t, outputGame.IsFieldSelected("fenString"))
+	assert.False(t, outputGame.IsFieldSelected("board"))
+	assert.False(t, outputGame.IsFieldSelected("unknown"))
+
+	outputGame, err = New().ParseGame(InputGame{})
+	require.NoError(t, err)
+	assert.True(t, outputGame.IsFieldSelected("board"))
+}
+
+func TestOutputGameFieldsWithoutActions(t *testing.T) {
+	a := New(WithOutputGameFields("fenString", "isCheckmate", "canClaimDraw"))
+
+	outputGame, err := a.ParseGame(InputGame{})
+	require.NoError(t, err)
+	assert.Nil(t, outputGame.Actions)
+	assert.Nil(t, outputGame.BlackPieces)
+	assert.Equal(t, Board{}, outputGame.Board)
+
+	// Flags that depend on the valid actions are still calculated
+	outputGame, _, err = a.DoAction(
+		InputGame{FENString: "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2"},
+		InputAction{FromSquare: "d8", ToSquare: "h4"},
+	)
+	require.NoError(t, err)
+	assert.True(t, outputGame.IsCheckmate)
+	assert.Nil(t, outputGame.Actions)
+
+	outputGame, err = a.ParseGame(InputGame{FENString: "4k3/8/8/8/8/8/8/4K2R w K - 100 80"})
+	require.NoError(t, err)
+	assert.True(t, outputGame.CanClaimDraw)
+	assert.Nil(t, outputGame.Actions)
+}
+
+func TestStepGameFields(t *testing.T) {
+	a := New(WithStepGameFields("fenString"))
+
+	outputGame, steps, _, err := a.ParseNotation(InputGame{}, "1. e4 e5 2. Nf3", "algebraic", NotationCharacteristics{})
+	require.NoError(t, err)
+	assert.True(t, outputGame.IsFieldSelected("actions"))
+	require.Len(t, steps, 3)
+	for _, step := range steps[:2] {
+		byts, err := json.Marshal(step.Game)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"fenString":"`+step.Game.FENString+`"}`, string(byts))
+	}
+	assert.True(t, steps[2].Game.IsFieldSelected("actions"))
+	assert.Len(t, steps[2].Game.Actions, 29)
+	assert.Len(t, steps[2].Game.NonMoveActions, 3) // Resign, timeout and offer draw
+
+	outputPGN, err := a.ParsePGN("1. e4 (1. d4 d5) e5 *")
+	require.NoError(t, err)
+	require.Len(t, outputPGN.OutputGameSteps, 2)
+	assert.False(t, outputPGN.OutputGameSteps[0].Game.IsFieldSelected("actions"))
+	assert.True(t, outputPGN.OutputGameSteps[1].Game.IsFieldSelected("actions"))
+	for _, step := range outputPGN.OutputGameSteps[0].Variations[0] {
+		assert.False(t, step.Game.IsFieldSelected("actions"))
+	}
+
+	// Options may be applied to a single call
+	_, steps, _, err = New().WithOptions(WithOutputGameFields("fenString")).ParseNotation(InputGame{}, "1. e4 e5", "algebraic", NotationCharacteristics{})
+	require.NoError(t, err)
+	assert.False(t, steps[0].Game.IsFieldSelected("actions"))
+	assert.False(t, steps[1].Game.IsFieldSelected("actions"))
+}
+
+func TestOutputGameFieldsErrors(t *testing.T) {
+	a := New(WithOutputGameFields("fenString", "fen"))
+	_, err := a.ParseGame(InputGame{})
+	assert.Equal(t, errUnknownOutputGameField, err)
+	_, _, _, err = a.ParseNotation(InputGame{}, "1. e4", "algebraic", NotationCharacteristics{})
+	assert.Equal(t, errUnknownOutputGameField, err)
+	_, err = a.ParsePGN("1. e4 *")
+	assert.Equal(t, errUnknownOutputGameField, err)
+
+	_, err = New(WithStepGameFields("")).ParseGame(InputGame{})
+	assert.Equal(t, errUnknownOutputGameField, err)
+
+	// A valid option after an invalid one doesn't clear the error
+	_, err = New(WithOutputGameFields("fen"), WithStepGameFields("fenString")).ParseGame(InputGame{})
+	assert.Equal(t, errUnknownOutputGameField, err)
+	_, err = New(WithStepGameFields("fen"), WithOutputGameFields("fenString")).ParseGame(InputGame{})
+	assert.Equal(t, errUnknownOutputGameField, err)
+}
@@ -0,0 +1,95 @@
+package api
+
+import "sort"
+
+// perftCounts are the statistics of the leaf games of a perft walk, i.e. the games after exactly `depth` actions.
+// Captures include en passant captures.
+type perftCounts struct {
+	nodes      int
+	captures   int
+	enPassants int
+	castles    int
+	promotions int
+	checks     int
+	checkmates int
+}
+
+func (c *perftCounts) add(o perftCounts) {
+	c.nodes += o.nodes
+	c.captures += o.captures
+	c.enPassants += o.enPassants
+	c.castles += o.castles
+	c.promotions += o.promotions
+	c.checks += o.checks
+	c.checkmates += o.checkmates
+}
+
+// perftDivide are the statistics of the subtree of a root action of a perft walk.
+type perftDivide struct {
+	a      action
+	counts perftCounts
+}
+
+// perft walks the tree of all valid actions that move pieces, up to the given depth, and counts the leaf games. It
+// also returns the counts of the subtree of every root action, sorted by the action in UCI notation.
+//
+// Only actions that move pieces are walked, and whether the game is over is ignored (e.g. draws by the 75-move rule),
+// so that the counts are comparable with the standard perft results.
+func (g game) perft(depth int) (perftCounts, []perftDivide) {
+	if depth <= 0 {
+		return perftCounts{nodes: 1}, []perftDivide{}
+	}
+	g = g.clone() // makeAction modifies the pieces maps in place
+	var (
+		total  perftCounts
+		divide = []perftDivide{}
+	)
+	for _, a := range g.calculateMoves() {
+		u := g.makeAction(a)
+		counts := g.perftWalk(a, depth-1)
+		g.unmakeAction(u)
+		total.add(counts)
+		divide = append(divide, perftDivide{a: a, counts: counts})
+	}
+	sort.Slice(divide, func(i, j int) bool {
+		return emitUCI(g, gameStep{a: divide[i].a}) < emitUCI(g, gameStep{a: divide[j].a})
+	})
+	return total, divide
+}
+
+func (g *game) perftWalk(lastAction action, depth int) perftCounts {
+	if depth == 0 {
+		return g.perftLeaf(lastAction)
+	}
+	var counts perftCounts
+	for _, a := range g.calculateMoves() {
+		u := g.makeAction(a)
+		counts.add(g.perftWalk(a, depth-1))
+		g.unmakeAction(u)
+	}
+	return counts
+}
+
+func (g *game) perftLeaf(lastAction action) perftCounts {
+	counts := perftCounts{nodes: 1}
+	if lastAction.isCapture {
+		counts.captures++
+	}
+	if lastAction.isEnPassantCapture {
+		counts.enPassants++
+	}
+	if lastAction.isCastle {
+		counts.castles++
+	}
+	if lastAction.isPromotion {
+		counts.promotions++
+	}
+	turn := g.turn()
+	if g.toBitboards().attackersOf(squareOf(g.kings[turn].xy), opponent(turn)) != 0 {
+		counts.checks++
+		if len(g.calculateMoves()) == 0 {
+			counts.checkmates++
+		}
+	}
+	return counts
+}
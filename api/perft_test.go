@@ -0,0 +1,90 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Expected counts are the published results of the standard perft positions. Positions 5 and 6 only have published
+// node counts.
+func TestPerft(t *testing.T) {
+	var (
+		kiwipete  = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+		position3 = "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"
+		position4 = "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1"
+		position5 = "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8"
+		position6 = "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10"
+	)
+	ts := []struct {
+		name          string
+		fen           string
+		depth         int
+		expected      PerftCounts
+		onlyNodeCount bool
+	}{
+		{name: "initial, depth 0", fen: defaultFEN, depth: 0, expected: PerftCounts{Nodes: 1}},
+		{name: "initial, depth 1", fen: defaultFEN, depth: 1, expected: PerftCounts{Nodes: 20}},
+		{name: "initial, depth 2", fen: defaultFEN, depth: 2, expected: PerftCounts{Nodes: 400}},
+		{name: "initial, depth 3", fen: defaultFEN, depth: 3, expected: PerftCounts{Nodes: 8902, Captures: 34, Checks: 12}},
+		{name: "initial, depth 4", fen: defaultFEN, depth: 4, expected: PerftCounts{Nodes: 197281, Captures: 1576, Checks: 469, Checkmates: 8}},
+		{name: "Kiwipete, depth 1", fen: kiwipete, depth: 1, expected: PerftCounts{Nodes: 48, Captures: 8, Castles: 2}},
+		{name: "Kiwipete, depth 2", fen: kiwipete, depth: 2, expected: PerftCounts{Nodes: 2039, Captures: 351, EnPassants: 1, Castles: 91, Checks: 3}},
+		{name: "Kiwipete, depth 3", fen: kiwipete, depth: 3, expected: PerftCounts{Nodes: 97862, Captures: 17102, EnPassants: 45, Castles: 3162, Checks: 993, Checkmates: 1}},
+		{name: "position 3, depth 1", fen: position3, depth: 1, expected: PerftCounts{Nodes: 14, Captures: 1, Checks: 2}},
+		{name: "position 3, depth 2", fen: position3, depth: 2, expected: PerftCounts{Nodes: 191, Captures: 14, Checks: 10}},
+		{name: "position 3, depth 3", fen: position3, depth: 3, expected: PerftCounts{Nodes: 2812, Captures: 209, EnPassants: 2, Checks: 267}},
+		{name: "position 3, depth 4", fen: position3, depth: 4, expected: PerftCounts{Nodes: 43238, Captures: 3348, EnPassants: 123, Checks: 1680, Checkmates: 17}},
+		{name: "position 4, depth 1", fen: position4, depth: 1, expected: PerftCounts{Nodes: 6}},
+		{name: "position 4, depth 2", fen: position4, depth: 2, expected: PerftCounts{Nodes: 264, Captures: 87, Castles: 6, Promotions: 48, Checks: 10}},
+		{name: "position 4, depth 3", fen: position4, depth: 3, expected: PerftCounts{Nodes: 9467, Captures: 1021, EnPassants: 4, Promotions: 120, Checks: 38, Checkmates: 22}},
+		{name: "position 5, depth 1", fen: position5, depth: 1, expected: PerftCounts{Nodes: 44}, onlyNodeCount: true},
+		{name: "position 5, depth 2", fen: position5, depth: 2, expected: PerftCounts{Nodes: 1486}, onlyNodeCount: true},
+		{name: "position 5, depth 3", fen: position5, depth: 3, expected: PerftCounts{Nodes: 62379}, onlyNodeCount: true},
+		{name: "position 6, depth 1", fen: position6, depth: 1, expected: PerftCounts{Nodes: 46}, onlyNodeCount: true},
+		{name: "position 6, depth 2", fen: position6, depth: 2, expected: PerftCounts{Nodes: 2079}, onlyNodeCount: true},
+		{name: "position 6, depth 3", fen: position6, depth: 3, expected: PerftCounts{Nodes: 89890}, onlyNodeCount: true},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := New().Perft(InputGame{FENString: tc.fen}, tc.depth)
+			require.NoError(t, err)
+			if tc.onlyNodeCount {
+				assert.Equal(t, tc.expected.Nodes, actual.Nodes)
+				return
+			}
+			assert.Equal(t, tc.expected, actual.PerftCounts)
+		})
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	actual, err := New().Perft(InputGame{FENString: defaultFEN}, 3)
+	require.NoError(t, err)
+	require.Len(t, actual.Divide, 20)
+
+	var sum PerftCounts
+	for i, d := range actual.Divide {
+		if i > 0 {
+			assert.True(t, actual.Divide[i-1].ActionString < d.ActionString, "divide is not sorted by actionString")
+		}
+		sum.Nodes += d.Nodes
+		sum.Captures += d.Captures
+		sum.Checks += d.Checks
+	}
+	assert.Equal(t, actual.Nodes, sum.Nodes)
+	assert.Equal(t, actual.Captures, sum.Captures)
+	assert.Equal(t, actual.Checks, sum.Checks)
+
+	assert.Equal(t, "a2a3", actual.Divide[0].ActionString)
+	assert.Equal(t, OutputAction{FromPieceOwner: "White", FromPieceType: "Pawn", FromPieceSquare: "a2", ToSquare: "a3", UCI: "a2a3", SAN: "a3", LAN: "a2-a3"}, actual.Divide[0].Action)
+	assert.Equal(t, 380, actual.Divide[0].Nodes)
+	assert.Equal(t, "e2e4", actual.Divide[11].ActionString)
+	assert.Equal(t, 600, actual.Divide[11].Nodes)
+}
+
+func TestPerftErrors(t *testing.T) {
+	_, err := New().Perft(InputGame{FENString: defaultFEN}, -1)
+	assert.Equal(t, errInvalidPerftDepth, err)
+}
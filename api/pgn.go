@@ -0,0 +1,276 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pgnError is an error found while parsing a PGN string, at the given byte offset of the string.
+type pgnError struct {
+	offset int
+	err    error
+}
+
+func (e pgnError) Error() string {
+	return fmt.Sprintf("at index %v: %v", e.offset, e.err)
+}
+
+type pgnTokenType int
+
+const (
+	pgnTokenTag = iota
+	pgnTokenComment
+	pgnTokenNAG
+	pgnTokenVariationStart
+	pgnTokenVariationEnd
+	pgnTokenResult
+	pgnTokenMoveNumber
+	pgnTokenAction
+)
+
+type pgnToken struct {
+	tokenType pgnTokenType
+	s         string
+	values    []string
+	offset    int
+}
+
+// pgnTokenRegexps are tried in order; the first one that matches wins. Escaped lines (i.e. starting with `%`) and
+// whitespace are handled separately by the tokenizer.
+var pgnTokenRegexps = []struct {
+	tokenType pgnTokenType
+	rx        *regexp.Regexp
+}{
+	{pgnTokenTag, regexp.MustCompile(`^\[\s*([A-Za-z0-9_]+)\s+"((?:[^"\\]|\\.)*)"\s*\]`)},
+	{pgnTokenComment, regexp.MustCompile(`^\{([^}]*)\}`)},
+	{pgnTokenComment, regexp.MustCompile(`^;([^\n]*)`)},
+	{pgnTokenNAG, regexp.MustCompile(`^\$([0-9]+)`)},
+	{pgnTokenVariationStart, regexp.MustCompile(`^\(`)},
+	{pgnTokenVariationEnd, regexp.MustCompile(`^\)`)},
+	{pgnTokenResult, regexp.MustCompile(`^(1-0|0-1|1/2-1/2|\*)`)},
+	{pgnTokenAction, regexp.MustCompile(`^(O-O-O|O-O|0-0-0|0-0)([^\s{}()\[\];$]*)`)},
+	{pgnTokenMoveNumber, regexp.MustCompile(`^[0-9]+\.*`)},
+	{pgnTokenAction, regexp.MustCompile(`^([A-Za-z][^\s{}()\[\];$]*)`)},
+}
+
+// pgnSuffixAnnotations maps the move suffix annotations to their equivalent Numeric Annotation Glyphs.
+var pgnSuffixAnnotations = []struct {
+	suffix string
+	nag    int
+}{
+	{"!!", 3},
+	{"??", 4},
+	{"!?", 5},
+	{"?!", 6},
+	{"!", 1},
+	{"?", 2},
+}
+
+func tokenizePGN(s string) ([]pgnToken, error) {
+	tokens := []pgnToken{}
+	i := 0
+	for i < len(s) {
+		switch {
+		case strings.ContainsRune(" \t\r\n\f\v", rune(s[i])):
+			i++
+			continue
+		case s[i] == '%' && (i == 0 || s[i-1] == '\n'):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		matched := false
+		for _, tr := range pgnTokenRegexps {
+			matches := tr.rx.FindStringSubmatch(s[i:])
+			if matches == nil {
+				continue
+			}
+			tokens = append(tokens, pgnToken{tokenType: tr.tokenType, s: matches[0], values: matches[1:], offset: i})
+			i += len(matches[0])
+			matched = true
+			break
+		}
+		if !matched {
+			return tokens, pgnError{i, fmt.Errorf("[%v] didn't match any token", firstLine(s[i:]))}
+		}
+	}
+	return tokens, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+type pgnGame struct {
+	tags        [][2]string
+	initialGame game
+	comments    []string
+	steps       []pgnStep
+	result      string
+}
+
+type pgnStep struct {
+	gameStep
+	comments   []string
+	nags       []int
+	variations [][]pgnStep
+}
+
+type pgnParser struct {
+	tokens         []pgnToken
+	i              int
+	s              string
+	actionResolver *notationParser
+}
+
+// parsePGN parses a single game in Portable Game Notation: tag pairs, followed by the movetext, which may contain
+// comments, Numeric Annotation Glyphs and (recursive) variations, and which may end with a game termination marker.
+func parsePGN(s string) (pgnGame, error) {
+	tokens, err := tokenizePGN(s)
+	if err != nil {
+		return pgnGame{}, err
+	}
+	p := &pgnParser{tokens: tokens, s: s, actionResolver: newNotationParserAlgebraic(characteristics{usesPieceLetters: []string{"en"}})}
+
+	var pg pgnGame
+	for p.i < len(p.tokens) && p.tokens[p.i].tokenType == pgnTokenTag {
+		pg.tags = append(pg.tags, [2]string{p.tokens[p.i].values[0], unescapePGNString(p.tokens[p.i].values[1])})
+		p.i++
+	}
+
+	fen := defaultFEN
+	if v, ok := pgnTagValue(pg.tags, "FEN"); ok {
+		fen = v
+	}
+	pg.initialGame, err = newGameFromFEN(fen)
+	if err != nil {
+		return pg, pgnError{0, err}
+	}
+
+	for p.i < len(p.tokens) && p.tokens[p.i].tokenType == pgnTokenComment {
+		pg.comments = append(pg.comments, strings.TrimSpace(p.tokens[p.i].values[0]))
+		p.i++
+	}
+
+	pg.steps, err = p.parseLine(pg.initialGame, false)
+	if err != nil {
+		return pg, err
+	}
+
+	if p.i < len(p.tokens) && p.tokens[p.i].tokenType == pgnTokenResult {
+		pg.result = p.tokens[p.i].s
+		p.i++
+	}
+	if p.i < len(p.tokens) {
+		return pg, pgnError{p.tokens[p.i].offset, fmt.Errorf("unexpected token %v after the end of the game", p.tokens[p.i].s)}
+	}
+	return pg, nil
+}
+
+// parseLine parses a sequence of actions starting from the given game, until the end of the tokens, a game
+// termination marker, or the end of the variation (only if isVariation).
+func (p *pgnParser) parseLine(initialGame game, isVariation bool) ([]pgnStep, error) {
+	var (
+		steps           = []pgnStep{}
+		pendingComments []string
+	)
+	currentGame := func() game {
+		if len(steps) == 0 {
+			return initialGame
+		}
+		return steps[len(steps)-1].g
+	}
+	for p.i < len(p.tokens) {
+		t := p.tokens[p.i]
+		switch t.tokenType {
+		case pgnTokenTag:
+			return steps, pgnError{t.offset, fmt.Errorf("unexpected tag pair %v within movetext", t.s)}
+		case pgnTokenResult:
+			if isVariation {
+				p.i++
+				continue
+			}
+			return steps, nil
+		case pgnTokenVariationEnd:
+			if !isVariation {
+				return steps, pgnError{t.offset, fmt.Errorf("unexpected end of variation")}
+			}
+			return steps, nil
+		case pgnTokenMoveNumber:
+			p.i++
+		case pgnTokenComment:
+			comment := strings.TrimSpace(t.values[0])
+			if len(steps) == 0 {
+				pendingComments = append(pendingComments, comment)
+			} else {
+				steps[len(steps)-1].comments = append(steps[len(steps)-1].comments, comment)
+			}
+			p.i++
+		case pgnTokenNAG:
+			if len(steps) == 0 {
+				return steps, pgnError{t.offset, fmt.Errorf("Numeric Annotation Glyph %v doesn't follow any action", t.s)}
+			}
+			nag, _ := strconv.Atoi(t.values[0]) // The regex cannot pass a non-number here
+			steps[len(steps)-1].nags = append(steps[len(steps)-1].nags, nag)
+			p.i++
+		case pgnTokenVariationStart:
+			if len(steps) == 0 {
+				return steps, pgnError{t.offset, fmt.Errorf("variation doesn't follow any action")}
+			}
+			p.i++
+			// A variation is an alternative to the last action, so it starts from the game prior to it
+			variationGame := initialGame
+			if len(steps) > 1 {
+				variationGame = steps[len(steps)-2].g
+			}
+			variation, err := p.parseLine(variationGame, true)
+			if err != nil {
+				return steps, err
+			}
+			if p.i >= len(p.tokens) {
+				return steps, pgnError{len(p.s), fmt.Errorf("variation is never closed")}
+			}
+			p.i++ // Skip the end of the variation
+			steps[len(steps)-1].variations = append(steps[len(steps)-1].variations, variation)
+		case pgnTokenAction:
+			token, nags := splitSuffixAnnotations(t.s)
+			gs, err := p.actionResolver.parseAction(currentGame(), token)
+			if err != nil {
+				return steps, pgnError{t.offset, err}
+			}
+			steps = append(steps, pgnStep{gameStep: gs, comments: pendingComments, nags: nags})
+			pendingComments = nil
+			p.i++
+		}
+	}
+	return steps, nil
+}
+
+func splitSuffixAnnotations(token string) (string, []int) {
+	for _, sa := range pgnSuffixAnnotations {
+		if strings.HasSuffix(token, sa.suffix) {
+			return strings.TrimSuffix(token, sa.suffix), []int{sa.nag}
+		}
+	}
+	return token, nil
+}
+
+func unescapePGNString(s string) string {
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+func pgnTagValue(tags [][2]string, name string) (string, bool) {
+	for _, tag := range tags {
+		if tag[0] == name {
+			return tag[1], true
+		}
+	}
+	return "", false
+}
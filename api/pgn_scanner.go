@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxPGNGameSize is the maximum size in bytes of a single game read by a PGNScanner. Larger games are skipped and
+// reported as errors, so that a corrupt database can't exhaust memory.
+const maxPGNGameSize = 1 << 20
+
+var errPGNGameTooLarge = fmt.Errorf("game is larger than %v bytes", maxPGNGameSize)
+
+// PGNError is an error found while parsing a game in Portable Game Notation. It
+// describes where the problem is, both as a byte offset and as a line and column,
+// relative to the beginning of the parsed string or reader. Lines and columns
+// start at 1. Like NotationError's, `column` is counted in characters (i.e.
+// Unicode code points), and `columnUTF16` in UTF-16 code units.
+type PGNError struct {
+	Offset      int    `json:"offset"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	ColumnUTF16 int    `json:"columnUTF16"`
+	Message     string `json:"error"`
+}
+
+func (e PGNError) Error() string {
+	return fmt.Sprintf("at line %v, column %v (offset %v): %v", e.Line, e.Column, e.Offset, e.Message)
+}
+
+// newPGNError calculates the position of an error found while parsing the string s, which starts at the given
+// offset and line of a larger string or reader.
+func newPGNError(s string, baseOffset int, baseLine int, err error) PGNError {
+	var (
+		offset int
+		pe     pgnError
+	)
+	if errors.As(err, &pe) {
+		offset, err = pe.offset, pe.err
+	}
+	if offset > len(s) {
+		offset = len(s)
+	}
+	column, columnUTF16 := columnsAt(s, offset)
+	return PGNError{
+		Offset:      baseOffset + offset,
+		Line:        baseLine + strings.Count(s[:offset], "\n"),
+		Column:      column,
+		ColumnUTF16: columnUTF16,
+		Message:     err.Error(),
+	}
+}
+
+// PGNScanner reads a database of games in Portable Game Notation from a reader, one
+// game at a time, so that only one game is in memory at any given time.
+//
+// A game that can't be parsed doesn't stop the scanner; the error is reported for
+// that game only, and scanning continues with the next one:
+//
+//	s := api.NewPGNScanner(f)
+//	for s.Scan() {
+//		outputPGN, err := s.Game()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type PGNScanner struct {
+	r           *bufio.Reader
+	offset      int
+	line        int
+	pendingLine string
+	pendingN    int
+	output      outputOptions
+	game        OutputPGN
+	gameErr     error
+	err         error
+}
+
+// NewPGNScanner constructs a PGNScanner that reads from r, and outputs games with the
+// default options. Use API.NewPGNScanner to output them with the API's options.
+func NewPGNScanner(r io.Reader) *PGNScanner {
+	return &PGNScanner{r: bufio.NewReader(r), line: 1}
+}
+
+// NewPGNScanner constructs a PGNScanner that reads from r, and outputs games with the
+// API's options, like ParsePGN does (e.g. WithSortedPieceArrays or WithOutputGameFields).
+func (a API) NewPGNScanner(r io.Reader) *PGNScanner {
+	return &PGNScanner{r: bufio.NewReader(r), line: 1, output: a.output}
+}
+
+// Scan advances the scanner to the next game, which is then available through Game.
+// It returns false when there are no more games, or when reading fails, in which
+// case Err returns the error.
+func (s *PGNScanner) Scan() bool {
+	var (
+		sb          strings.Builder
+		startOffset = -1
+		startLine   int
+		isMovetext  bool
+		isTooLarge  bool
+		nesting     pgnNesting
+	)
+	for {
+		line, n, err := s.readLine()
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		trimmed := strings.TrimSpace(line)
+
+		// A tag pair after the movetext is the beginning of the next game, unless it's within a comment or variation
+		if isMovetext && !nesting.isNested() && strings.HasPrefix(trimmed, "[") {
+			s.pendingLine, s.pendingN = line, n
+			break
+		}
+		if trimmed != "" && startOffset == -1 {
+			startOffset, startLine = s.offset, s.line
+		}
+		if nesting.isNested() || (trimmed != "" && !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "%")) {
+			isMovetext = true
+			nesting.update(line)
+		}
+		if startOffset != -1 && !isTooLarge {
+			sb.WriteString(line)
+			if sb.Len() > maxPGNGameSize {
+				isTooLarge = true
+				sb.Reset()
+			}
+		}
+		s.offset += n
+		s.line += strings.Count(line, "\n")
+
+		if err == io.EOF {
+			break
+		}
+	}
+	if startOffset == -1 {
+		return false
+	}
+
+	s.game, s.gameErr = OutputPGN{}, nil
+	if s.output.err != nil {
+		s.gameErr = s.output.err
+		return true
+	}
+	if isTooLarge {
+		s.gameErr = PGNError{Offset: startOffset, Line: startLine, Column: 1, ColumnUTF16: 1, Message: errPGNGameTooLarge.Error()}
+		return true
+	}
+	pg, err := parsePGN(sb.String())
+	if err != nil {
+		s.gameErr = newPGNError(sb.String(), startOffset, startLine, err)
+		return true
+	}
+	s.game = mapPGNGameToOutputPGN(pg, s.output)
+	return true
+}
+
+// Game returns the game read by the last call to Scan, or a PGNError describing why
+// it could not be parsed.
+func (s *PGNScanner) Game() (OutputPGN, error) {
+	return s.game, s.gameErr
+}
+
+// Err returns the first error that was found while reading, other than io.EOF.
+// Errors parsing specific games are not returned here; see Game.
+func (s *PGNScanner) Err() error {
+	return s.err
+}
+
+// readLine reads the next line, including its `\n`, and its length in bytes. The middle of lines much longer than
+// maxPGNGameSize is dropped, so that a single line can't exhaust memory either; such lines make the game too large
+// anyway.
+func (s *PGNScanner) readLine() (string, int, error) {
+	if s.pendingLine != "" {
+		line, n := s.pendingLine, s.pendingN
+		s.pendingLine, s.pendingN = "", 0
+		return line, n, nil
+	}
+	var (
+		sb strings.Builder
+		n  int
+	)
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		n += len(chunk)
+		if sb.Len() <= maxPGNGameSize || err != bufio.ErrBufferFull {
+			sb.Write(chunk)
+		}
+		if err != bufio.ErrBufferFull {
+			return sb.String(), n, err
+		}
+	}
+}
+
+// pgnNesting tracks whether a PGN reader is within a brace comment or a recursive variation, which may span many lines,
+// and so contain lines that look like the tag pairs of the next game.
+type pgnNesting struct {
+	isInComment    bool
+	variationDepth int
+}
+
+func (n pgnNesting) isNested() bool {
+	return n.isInComment || n.variationDepth > 0
+}
+
+// update advances the nesting through a line of movetext.
+func (n *pgnNesting) update(line string) {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case n.isInComment:
+			n.isInComment = line[i] != '}'
+		case line[i] == '{':
+			n.isInComment = true
+		case line[i] == ';':
+			return // The rest of the line is a comment
+		case line[i] == '(':
+			n.variationDepth++
+		case line[i] == ')' && n.variationDepth > 0:
+			n.variationDepth--
+		}
+	}
+}
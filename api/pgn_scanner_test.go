@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pgnDatabase = `[Event "First"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0
+
+[Event "Second"]
+[Result "*"]
+
+1. e4 e5
+2. Bc5 Nc6 *
+
+[Event "Third"]
+[Result "*"]
+
+% An escaped line
+1. d4 d5 *
+`
+
+func TestPGNScanner(t *testing.T) {
+	var (
+		s      = NewPGNScanner(strings.NewReader(pgnDatabase))
+		events []string
+		errs   []error
+	)
+	for s.Scan() {
+		outputPGN, err := s.Game()
+		errs = append(errs, err)
+		if err != nil {
+			continue
+		}
+		events = append(events, outputPGN.Tags[0].Value)
+	}
+	require.NoError(t, s.Err())
+	assert.Equal(t, []string{"First", "Third"}, events)
+	assert.Equal(t, []error{
+		nil,
+		PGNError{Offset: 120, Line: 10, Column: 4, ColumnUTF16: 4, Message: "token Bc5 didn't match any valid action"},
+		nil,
+	}, errs)
+	assert.Equal(t, "Bc5", pgnDatabase[120:123])
+}
+
+func TestPGNScannerErrorColumns(t *testing.T) {
+	pgn := `[Event "Columns"]` + "\n\n1. e4 {Ruy López 𝄞} e5 2. Bc5 *\n"
+	s := NewPGNScanner(strings.NewReader(pgn))
+
+	require.True(t, s.Scan())
+	_, err := s.Game()
+	assert.Equal(t, PGNError{Offset: 49, Line: 3, Column: 27, ColumnUTF16: 28, Message: "token Bc5 didn't match any valid action"}, err)
+	assert.Equal(t, "Bc5", pgn[49:52])
+}
+
+func TestPGNScannerGameTooLarge(t *testing.T) {
+	pgn := `[Event "Large"]` + "\n\n" + strings.Repeat("{comment} ", maxPGNGameSize/10+1) + "*\n\n" + `[Event "Small"]` + "\n\n1. e4 *\n"
+	s := NewPGNScanner(strings.NewReader(pgn))
+
+	require.True(t, s.Scan())
+	_, err := s.Game()
+	assert.Equal(t, PGNError{Offset: 0, Line: 1, Column: 1, ColumnUTF16: 1, Message: errPGNGameTooLarge.Error()}, err)
+
+	require.True(t, s.Scan())
+	outputPGN, err := s.Game()
+	require.NoError(t, err)
+	assert.Equal(t, "Small", outputPGN.Tags[0].Value)
+
+	assert.False(t, s.Scan())
+	assert.NoError(t, s.Err())
+}
+
+func TestPGNScannerNestedLinesLookingLikeTags(t *testing.T) {
+	pgn := `[Event "First"]
+
+1. e4 {a comment
+[that looks like a tag]} e5 (1... c5 {another
+[one]}
+2. Nf3) *
+
+[Event "Second"]
+
+1. d4 *
+`
+	var (
+		s      = NewPGNScanner(strings.NewReader(pgn))
+		events []string
+	)
+	for s.Scan() {
+		outputPGN, err := s.Game()
+		require.NoError(t, err)
+		events = append(events, outputPGN.Tags[0].Value)
+	}
+	require.NoError(t, s.Err())
+	assert.Equal(t, []string{"First", "Second"}, events)
+}
+
+func TestPGNScannerLineTooLarge(t *testing.T) {
+	pgn := `[Event "Large"]` + "\n\n{" + strings.Repeat("x", 2*maxPGNGameSize) + "} *\n\n" + `[Event "Small"]` + "\n\n1. e4 *\n"
+	s := NewPGNScanner(strings.NewReader(pgn))
+
+	require.True(t, s.Scan())
+	_, err := s.Game()
+	assert.Equal(t, PGNError{Offset: 0, Line: 1, Column: 1, ColumnUTF16: 1, Message: errPGNGameTooLarge.Error()}, err)
+
+	require.True(t, s.Scan())
+	outputPGN, err := s.Game()
+	require.NoError(t, err)
+	assert.Equal(t, "Small", outputPGN.Tags[0].Value)
+	assert.Equal(t, len(pgn), s.offset)
+
+	assert.False(t, s.Scan())
+	assert.NoError(t, s.Err())
+}
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read(p []byte) (int, error) { return 0, r.err }
+
+func TestPGNScannerReadError(t *testing.T) {
+	expectedErr := errors.New("disk on fire")
+	s := NewPGNScanner(io.MultiReader(strings.NewReader("1. e4 e5\n"), failingReader{expectedErr}))
+	assert.False(t, s.Scan())
+	assert.Equal(t, expectedErr, s.Err())
+}
+
+func TestPGNScannerAPIOptions(t *testing.T) {
+	s := New(WithSortedPieceArrays(), WithOutputGameFields("fenString", "whitePieceList")).NewPGNScanner(strings.NewReader(pgnDatabase))
+	require.True(t, s.Scan())
+	outputPGN, err := s.Game()
+	require.NoError(t, err)
+	assert.Len(t, outputPGN.Game.WhitePieceList, 16)
+	assert.False(t, outputPGN.Game.IsFieldSelected("actions"))
+	assert.Nil(t, outputPGN.Game.Actions)
+
+	s = New(WithOutputGameFields("unknown")).NewPGNScanner(strings.NewReader(pgnDatabase))
+	require.True(t, s.Scan())
+	_, err = s.Game()
+	assert.Equal(t, errUnknownOutputGameField, err)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePGN(t *testing.T) {
+	testCases := []struct {
+		name               string
+		s                  string
+		expectedErr        error
+		expectedTags       [][2]string
+		expectedTokens     []string
+		expectedComments   map[int][]string
+		expectedNAGs       map[int][]int
+		expectedVariations map[int][][]string
+		expectedResult     string
+		expectedFEN        string
+	}{
+		{
+			name: "full PGN with tags, comments, NAGs and recursive variations",
+			s: `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Date "1992.11.04"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+
+{Opening comment} 1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6 $1
+4. Ba4 (4. Bxc6 dxc6 (4... bxc6 5. O-O) 5. O-O) 4... Nf6 5. O-O!? ; the main line
+Be7 1/2-1/2`,
+			expectedTags: [][2]string{
+				{"Event", "F/S Return Match"},
+				{"Site", "Belgrade, Serbia JUG"},
+				{"Date", "1992.11.04"},
+				{"Round", "29"},
+				{"White", "Fischer, Robert J."},
+				{"Black", "Spassky, Boris V."},
+				{"Result", "1/2-1/2"},
+			},
+			expectedTokens: []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O", "Be7"},
+			expectedComments: map[int][]string{
+				4: {"This opening is called the Ruy Lopez."},
+				8: {"the main line"},
+			},
+			expectedNAGs: map[int][]int{
+				5: {1},
+				8: {5},
+			},
+			expectedVariations: map[int][][]string{
+				6: {{"Bxc6", "dxc6", "O-O"}},
+			},
+			expectedResult: "1/2-1/2",
+			expectedFEN:    "r1bqk2r/1pppbppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQ1RK1 w kq - 4 6",
+		},
+		{
+			name: "starts from the FEN tag",
+			s: `[SetUp "1"]
+[FEN "8/8/8/8/8/1k5P/8/2K5 w - - 0 1"]
+
+1. h4 Kc4 2. h5 Kd5 3. h6 Ke6 4. h7 Kf7 5. h8=Q *`,
+			expectedTags:   [][2]string{{"SetUp", "1"}, {"FEN", "8/8/8/8/8/1k5P/8/2K5 w - - 0 1"}},
+			expectedTokens: []string{"h4", "Kc4", "h5", "Kd5", "h6", "Ke6", "h7", "Kf7", "h8=Q"},
+			expectedResult: "*",
+			expectedFEN:    "7Q/5k2/8/8/8/8/8/2K5 b - - 0 5",
+		},
+		{
+			name:           "movetext without tags nor result",
+			s:              `1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6?? 4. Qxf7#`,
+			expectedTokens: []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"},
+			expectedNAGs:   map[int][]int{5: {4}},
+			expectedFEN:    "r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4",
+		},
+		{
+			name:        "invalid action",
+			s:           `1. e4 e5 2. Bc5`,
+			expectedErr: pgnError{12, errors.New("token Bc5 didn't match any valid action")},
+		},
+		{
+			name:        "ambiguous action",
+			s:           "[FEN \"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1\"]\n\n1. Nd2 *",
+			expectedErr: pgnError{46, fmt.Errorf("%w: Nd2 could be any of [Nfd2 Nbd2]", errAmbiguousSAN)},
+		},
+		{
+			name:        "unclosed variation",
+			s:           `1. e4 (1. d4 d5`,
+			expectedErr: pgnError{15, errors.New("variation is never closed")},
+		},
+		{
+			name:        "variation without action",
+			s:           `(1. e4) 1. d4`,
+			expectedErr: pgnError{0, errors.New("variation doesn't follow any action")},
+		},
+		{
+			name:        "invalid token",
+			s:           `1. e4 e5 2. @`,
+			expectedErr: pgnError{12, errors.New("[@] didn't match any token")},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test parse PGN %v: %v", i, tc.name), func(t *testing.T) {
+			pg, err := parsePGN(tc.s)
+			require.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedTags, pg.tags)
+			assert.Equal(t, tc.expectedResult, pg.result)
+			require.Len(t, pg.steps, len(tc.expectedTokens))
+			for i, step := range pg.steps {
+				assert.Equal(t, tc.expectedTokens[i], step.s)
+				assert.Equal(t, tc.expectedComments[i], step.comments)
+				assert.Equal(t, tc.expectedNAGs[i], step.nags)
+				var variations [][]string
+				for _, variation := range step.variations {
+					tokens := []string{}
+					for _, vs := range variation {
+						tokens = append(tokens, vs.s)
+					}
+					variations = append(variations, tokens)
+				}
+				assert.Equal(t, tc.expectedVariations[i], variations)
+			}
+			assert.Equal(t, tc.expectedFEN, pg.steps[len(pg.steps)-1].g.toFEN())
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const pgnMaxLineLength = 80
+
+// pgnSevenTagRoster are the tags that every PGN game must have, in the order they must appear, with their default
+// values for when they are unknown. The Result tag's value is always derived from the game.
+var pgnSevenTagRoster = [][2]string{
+	{"Event", "?"},
+	{"Site", "?"},
+	{"Date", "????.??.??"},
+	{"Round", "?"},
+	{"White", "?"},
+	{"Black", "?"},
+	{"Result", "*"},
+}
+
+// writePGN serialises a game in Portable Game Notation, in export format: the Seven Tag Roster first, followed by
+// the other tags, the SetUp and FEN tags when the game doesn't start from the default game, and the movetext in
+// Standard Algebraic Notation, wrapped at 80 columns and ending with the game termination marker.
+//
+// The actionString of the steps is ignored; every action is rendered again, so that it's correctly disambiguated.
+func writePGN(pg pgnGame) string {
+	result := pgnResult(pg)
+
+	tags := make([][2]string, len(pgnSevenTagRoster))
+	copy(tags, pgnSevenTagRoster)
+	for i := range tags {
+		if v, ok := pgnTagValue(pg.tags, tags[i][0]); ok {
+			tags[i][1] = v
+		}
+	}
+	tags[len(tags)-1][1] = result
+	for _, tag := range pg.tags {
+		if _, ok := pgnTagValue(tags, tag[0]); !ok && tag[0] != "SetUp" && tag[0] != "FEN" {
+			tags = append(tags, tag)
+		}
+	}
+	if fen := pg.initialGame.toFEN(); fen != defaultFEN {
+		tags = append(tags, [2]string{"SetUp", "1"}, [2]string{"FEN", fen})
+	}
+
+	var sb strings.Builder
+	for _, tag := range tags {
+		sb.WriteString(fmt.Sprintf("[%v \"%v\"]\n", tag[0], escapePGNString(tag[1])))
+	}
+	sb.WriteString("\n")
+
+	tokens := []string{}
+	for _, comment := range pg.comments {
+		tokens = append(tokens, pgnCommentTokens(comment)...)
+	}
+	tokens = append(tokens, pgnMovetextTokens(pg.initialGame, pg.steps)...)
+	tokens = append(tokens, result)
+	for _, line := range wrapPGNTokens(tokens) {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// pgnResult derives the game termination marker from the last game of the mainline.
+func pgnResult(pg pgnGame) string {
+	lastGame := pg.initialGame
+	if len(pg.steps) > 0 {
+		lastGame = pg.steps[len(pg.steps)-1].g
+	}
+	return lastGame.result()
+}
+
+func pgnMovetextTokens(initialGame game, steps []pgnStep) []string {
+	var (
+		tokens        = []string{}
+		prevGame      = initialGame
+		mustAddNumber = true
+	)
+	for _, step := range steps {
+		// Actions that don't move any piece (e.g. resigning) are not actions in PGN; at most, they're conveyed by the
+		// game termination marker
+		if !step.a.isMove() {
+			continue
+		}
+		// The move number is kept in the same token as the action, so that they are never wrapped apart
+		token := emitAlgebraic(prevGame, step.gameStep)
+		switch {
+		case prevGame.turn() == colorWhite:
+			token = fmt.Sprintf("%v. %v", prevGame.fullMoveNumber, token)
+		case mustAddNumber:
+			token = fmt.Sprintf("%v... %v", prevGame.fullMoveNumber, token)
+		}
+		tokens = append(tokens, token)
+		mustAddNumber = false
+
+		for _, nag := range step.nags {
+			tokens = append(tokens, fmt.Sprintf("$%v", nag))
+		}
+		for _, comment := range step.comments {
+			tokens = append(tokens, pgnCommentTokens(comment)...)
+			mustAddNumber = true
+		}
+		for _, variation := range step.variations {
+			variationTokens := pgnMovetextTokens(prevGame, variation)
+			if len(variationTokens) == 0 {
+				continue
+			}
+			variationTokens[0] = "(" + variationTokens[0]
+			variationTokens[len(variationTokens)-1] += ")"
+			tokens = append(tokens, variationTokens...)
+			mustAddNumber = true
+		}
+		prevGame = step.g
+	}
+	return tokens
+}
+
+// pgnCommentTokens splits a comment into words, so that it can be wrapped.
+func pgnCommentTokens(comment string) []string {
+	words := strings.Fields(strings.Replace(comment, "}", "", -1))
+	if len(words) == 0 {
+		return []string{"{}"}
+	}
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+	return words
+}
+
+func wrapPGNTokens(tokens []string) []string {
+	var (
+		lines = []string{}
+		line  strings.Builder
+	)
+	for _, token := range tokens {
+		if line.Len() > 0 && utf8.RuneCountInString(line.String())+1+utf8.RuneCountInString(token) > pgnMaxLineLength {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(token)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func escapePGNString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePGN(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pgn      string
+		expected string
+	}{
+		{
+			name: "Seven Tag Roster is completed, ordered and Result is derived",
+			pgn: `[White "Kasparov"]
+[Annotator "Me"]
+[Event "Casual"]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#`,
+			expected: `[Event "Casual"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Kasparov"]
+[Black "?"]
+[Result "1-0"]
+[Annotator "Me"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7# 1-0
+`,
+		},
+		{
+			name: "SetUp and FEN tags when not starting from the default game, disambiguation by file, rank and both",
+			pgn: `[FEN "4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1"]
+
+1. Qa1b2 Kd7 2. Qa3b3 Ke8 3. Qb3a2 Kd8`,
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1"]
+
+1. Qa1b2 Kd7 2. Qab3 Ke8 3. Q3a2 Kd8 *
+`,
+		},
+		{
+			name: "comments, NAGs and variations, wrapped at 80 columns",
+			pgn:  `{A classic trap.} 1. e4 e5 2. Bc4 (2. Nf3 Nc6 (2... d6) 3. Bb5) 2... Nc6 3. Qh5 {Threatening mate on f7, which is only defended by the king.} Nf6?? $18 4. Qxf7#`,
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1-0"]
+
+{A classic trap.} 1. e4 e5 2. Bc4 (2. Nf3 Nc6 (2... d6) 3. Bb5) 2... Nc6 3. Qh5
+{Threatening mate on f7, which is only defended by the king.} 3... Nf6 $4 $18
+4. Qxf7# 1-0
+`,
+		},
+		{
+			name: "stalemate is a draw",
+			pgn: `[FEN "k7/8/1K6/8/8/8/2Q5/8 w - - 0 1"]
+
+1. Qc7`,
+			expected: `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "1/2-1/2"]
+[SetUp "1"]
+[FEN "k7/8/1K6/8/8/8/2Q5/8 w - - 0 1"]
+
+1. Qc7 1/2-1/2
+`,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test write PGN %v: %v", i, tc.name), func(t *testing.T) {
+			pg, err := parsePGN(tc.pgn)
+			require.NoError(t, err)
+			actual := writePGN(pg)
+			assert.Equal(t, tc.expected, actual)
+
+			// The written PGN must be parseable, and produce the same PGN when written again
+			reparsed, err := parsePGN(actual)
+			require.NoError(t, err)
+			assert.Equal(t, actual, writePGN(reparsed))
+		})
+	}
+}
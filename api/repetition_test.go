@@ -0,0 +1,101 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepetition(t *testing.T) {
+	ts := []struct {
+		name                          string
+		fen                           string
+		s                             string
+		expectedErr                   bool
+		expectedRepetitionCount       int
+		expectedIsThreefoldRepetition bool
+		expectedIsFivefoldRepetition  bool
+		expectedCanClaimDraw          bool
+		expectedIsDraw                bool
+	}{
+		{
+			name:                    "initial position is repeated twice",
+			fen:                     defaultFEN,
+			s:                       `1. Nf3 Nf6 2. Ng1 Ng8`,
+			expectedRepetitionCount: 2,
+		},
+		{
+			name:                          "initial position is repeated thrice",
+			fen:                           defaultFEN,
+			s:                             `1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 Nf6 4. Ng1 Ng8`,
+			expectedRepetitionCount:       3,
+			expectedIsThreefoldRepetition: true,
+			expectedCanClaimDraw:          true,
+		},
+		{
+			name:                          "fivefold repetition ends the game in a draw",
+			fen:                           defaultFEN,
+			s:                             `1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 Nf6 4. Ng1 Ng8 5. Nf3 Nf6 6. Ng1 Ng8 7. Nf3 Nf6 8. Ng1 Ng8`,
+			expectedRepetitionCount:       5,
+			expectedIsThreefoldRepetition: true,
+			expectedIsFivefoldRepetition:  true,
+			expectedIsDraw:                true,
+		},
+		{
+			name:        "no actions after fivefold repetition",
+			fen:         defaultFEN,
+			s:           `1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 Nf6 4. Ng1 Ng8 5. Nf3 Nf6 6. Ng1 Ng8 7. Nf3 Nf6 8. Ng1 Ng8 9. e4`,
+			expectedErr: true,
+		},
+		{
+			name:                    "a pawn movement resets the history",
+			fen:                     defaultFEN,
+			s:                       `1. Nf3 Nf6 2. Ng1 Ng8 3. e3 e6 4. Nf3 Nf6 5. Ng1 Ng8`,
+			expectedRepetitionCount: 2,
+		},
+		{
+			name:                    "castling rights are part of the position",
+			fen:                     "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			s:                       `1. Ke2 Ke7 2. Ke1 Ke8 3. Ke2 Ke7 4. Ke1 Ke8`,
+			expectedRepetitionCount: 2,
+		},
+		{
+			name:                    "impossible en passant is not part of the position",
+			fen:                     "4k3/8/8/3p4/8/8/8/4K3 w - d6 0 1",
+			s:                       `1. Kd2 Kd7 2. Ke1 Ke8`,
+			expectedRepetitionCount: 2,
+		},
+		{
+			name:                    "en passant of a pinned Pawn is not part of the position, even though its key counts it",
+			fen:                     "4k3/8/8/K2pP2r/8/8/8/8 w - d6 0 1",
+			s:                       `1. Ka4 Kd7 2. Ka5 Ke8`,
+			expectedRepetitionCount: 2,
+		},
+		{
+			name:                    "possible en passant is part of the position",
+			fen:                     "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+			s:                       `1. Kd2 Kd7 2. Ke1 Ke8`,
+			expectedRepetitionCount: 1,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := newGameFromFEN(tc.fen)
+			require.NoError(t, err)
+			gameSteps, err := newNotationParserAlgebraic(characteristics{}).parse(g, tc.s)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			lastGame := gameSteps[len(gameSteps)-1].g
+			assert.Equal(t, tc.expectedRepetitionCount, lastGame.repetitionCount)
+			assert.Equal(t, tc.expectedIsThreefoldRepetition, lastGame.isThreefoldRepetition)
+			assert.Equal(t, tc.expectedIsFivefoldRepetition, lastGame.isFivefoldRepetition)
+			assert.Equal(t, tc.expectedCanClaimDraw, lastGame.canClaimDraw)
+			assert.Equal(t, tc.expectedIsDraw, lastGame.isDraw)
+			assert.Equal(t, tc.expectedIsDraw, lastGame.isGameOver)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import "sync"
+
+// workerPool bounds the number of goroutines that calculate actions in parallel, across all the games of an API
+// instance, so that a server parsing many games concurrently doesn't spawn an unbounded number of goroutines.
+type workerPool struct {
+	size   int
+	tokens chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: size, tokens: make(chan struct{}, size)}
+}
+
+// minSquaresPerChunk is the fewest pieces whose moves are worth calculating in another goroutine. Calculating the
+// moves of a piece takes under a microsecond, which is about the cost of handing it to another goroutine, so smaller
+// chunks are slower in parallel than sequentially.
+const minSquaresPerChunk = 8
+
+// calculateMoves calculates the same actions as game.calculateMoves, in the same order, by splitting the pieces on
+// the given squares into one chunk per worker, of at least minSquaresPerChunk pieces. The calling goroutine
+// calculates the last chunk, and also any chunk for which there's no worker available (e.g. when all of them are busy
+// with other games), so it never waits for a worker to be available.
+func (wp *workerPool) calculateMoves(g game, bbs bitboards, squares []int) []action {
+	chunkCount := wp.size
+	if maxChunkCount := len(squares) / minSquaresPerChunk; maxChunkCount < chunkCount {
+		chunkCount = maxChunkCount
+	}
+	if chunkCount <= 1 {
+		return g.calculateMovesOfSquares(bbs, squares)
+	}
+
+	var (
+		chunks = make([][]action, chunkCount)
+		wg     sync.WaitGroup
+	)
+	for i := range chunks {
+		from, to := i*len(squares)/chunkCount, (i+1)*len(squares)/chunkCount
+		if i == len(chunks)-1 {
+			chunks[i] = g.calculateMovesOfSquares(bbs, squares[from:to])
+			break
+		}
+		select {
+		case wp.tokens <- struct{}{}:
+			wg.Add(1)
+			go func(i, from, to int) {
+				defer func() {
+					<-wp.tokens
+					wg.Done()
+				}()
+				chunks[i] = g.calculateMovesOfSquares(bbs, squares[from:to])
+			}(i, from, to)
+		default:
+			chunks[i] = g.calculateMovesOfSquares(bbs, squares[from:to])
+		}
+	}
+	wg.Wait()
+
+	moves := []action{}
+	for _, chunk := range chunks {
+		moves = append(moves, chunk...)
+	}
+	return moves
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var workersTestFENs = []string{
+	defaultFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"4k3/8/8/8/8/8/8/4K3 w - - 0 1",
+}
+
+func TestParallelActions(t *testing.T) {
+	for _, workers := range []int{2, 3, 8, 32} {
+		for _, fen := range workersTestFENs {
+			expected, err := New().ParseGame(InputGame{FENString: fen})
+			require.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				actual, err := New(WithParallelActions(workers)).ParseGame(InputGame{FENString: fen})
+				require.NoError(t, err)
+				assert.Equal(t, expected, actual, "%v workers, %v", workers, fen)
+			}
+		}
+	}
+}
+
+func TestParallelActionsFollowingGames(t *testing.T) {
+	sequential, err := New().Perft(InputGame{FENString: workersTestFENs[1]}, 2)
+	require.NoError(t, err)
+	parallel, err := New(WithParallelActions(4)).Perft(InputGame{FENString: workersTestFENs[1]}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, sequential, parallel)
+
+	_, steps, _, err := New(WithParallelActions(4)).ParseNotation(InputGame{}, "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6", "algebraic", NotationCharacteristics{})
+	require.NoError(t, err)
+	expectedGame, err := New().ParseGame(InputGame{FENString: steps[len(steps)-1].Game.FENString})
+	require.NoError(t, err)
+	assert.Equal(t, expectedGame.Actions, steps[len(steps)-1].Game.Actions)
+}
+
+func TestParallelActionsConcurrentCalls(t *testing.T) {
+	var (
+		a  = New(WithParallelActions(2))
+		wg sync.WaitGroup
+	)
+	expected := make([]OutputGame, len(workersTestFENs))
+	for i, fen := range workersTestFENs {
+		expected[i], _ = New().ParseGame(InputGame{FENString: fen})
+	}
+	for i := 0; i < 20; i++ {
+		for j, fen := range workersTestFENs {
+			wg.Add(1)
+			go func(j int, fen string) {
+				defer wg.Done()
+				actual, err := a.ParseGame(InputGame{FENString: fen})
+				assert.NoError(t, err)
+				assert.Equal(t, expected[j], actual)
+			}(j, fen)
+		}
+	}
+	wg.Wait()
+}
+
+// The benchmarks calculate the actions of Kiwipete, a complex middlegame with 48 actions that move pieces. Note that
+// they only show a gain when run with more than one CPU (e.g. `-cpu 1,2,4`).
+func BenchmarkCalculateAllActions(b *testing.B) {
+	benchmarkCalculateAllActions(b, nil)
+}
+
+func BenchmarkCalculateAllActionsParallel2(b *testing.B) {
+	benchmarkCalculateAllActions(b, newWorkerPool(2))
+}
+
+func BenchmarkCalculateAllActionsParallel4(b *testing.B) {
+	benchmarkCalculateAllActions(b, newWorkerPool(4))
+}
+
+func BenchmarkCalculateAllActionsParallel8(b *testing.B) {
+	benchmarkCalculateAllActions(b, newWorkerPool(8))
+}
+
+func benchmarkCalculateAllActions(b *testing.B, workers *workerPool) {
+	g, err := newGameFromFEN(workersTestFENs[1])
+	require.NoError(b, err)
+	g.workers = workers
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.calculateAllActions()
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Expected keys are the test vectors of the Polyglot opening book format.
+func TestPositionHash(t *testing.T) {
+	ts := []struct {
+		name           string
+		notationString string
+		expected       map[int]string // By number of actions done
+	}{
+		{
+			name:           "default game, then moves including en passant that can't be captured, and King moves",
+			notationString: "1. e4 d5 2. e5 f5 3. Ke2 Kf7",
+			expected: map[int]string{
+				0: "463b96181691fc9c",
+				1: "823c9b50fd114196",
+				2: "0756b94461c50fb0",
+				3: "662fafb965db29d4",
+				4: "22a48b5a8e47ff78",
+				5: "652a607ca3f242c1",
+				6: "00fdd303c946bdd9",
+			},
+		},
+		{
+			name:           "en passant that can be captured, en passant capture, and castling rights lost by a Rook move",
+			notationString: "1. a4 b5 2. h4 b4 3. c4 bxc3 4. Ra3",
+			expected: map[int]string{
+				5: "3c8123ea7b067637",
+				7: "5c3f9b829b279560",
+			},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			outputGame, steps, _, err := New().ParseNotation(InputGame{}, tc.notationString, "algebraic", NotationCharacteristics{})
+			require.NoError(t, err)
+			actual := []string{outputGame.PositionHash}
+			for _, step := range steps {
+				actual = append(actual, step.Game.PositionHash)
+
+				// The incrementally updated key must be the same as the key of the same position parsed from scratch
+				parsedGame, err := New().ParseGame(InputGame{FENString: step.Game.FENString})
+				require.NoError(t, err)
+				assert.Equal(t, step.Game.PositionHash, parsedGame.PositionHash, step.ActionString)
+			}
+			for i, expected := range tc.expected {
+				require.True(t, i < len(actual))
+				assert.Equal(t, expected, actual[i], "after %v actions", i)
+			}
+		})
+	}
+}
+
+func TestPositionHashIsUpdatedIncrementally(t *testing.T) {
+	for _, fen := range []string{
+		defaultFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	} {
+		g, err := newGameFromFEN(fen)
+		require.NoError(t, err)
+		assertPositionHashIsUpdatedIncrementally(t, g, 2)
+	}
+}
+
+func assertPositionHashIsUpdatedIncrementally(t *testing.T, g game, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, a := range g.calculateMoves() {
+		newGame := g.doAction(a)
+		require.Equal(t, newGame.calculatePositionHash(), newGame.positionHash, "%v after %v", a, g.toFEN())
+		assertPositionHashIsUpdatedIncrementally(t, newGame, depth-1)
+	}
+}
@@ -3,20 +3,41 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/marianogappa/cheesse/api"
 	"net/http"
 	"os"
+	"strings"
 )
 
 //TODO: server and cli handling done separately
 
 var a = api.New()
 
+// outputGameFieldsOptions are the options to select OutputGame fields, from comma-separated lists of their JSON names.
+func outputGameFieldsOptions(fields string, stepFields string) []api.Option {
+	opts := []api.Option{}
+	if fields != "" {
+		opts = append(opts, api.WithOutputGameFields(strings.Split(fields, ",")...))
+	}
+	if stepFields != "" {
+		opts = append(opts, api.WithStepGameFields(strings.Split(stepFields, ",")...))
+	}
+	return opts
+}
+
+// requestAPI configures the API for a single server request, with the `fields` and `stepFields` query parameters,
+// e.g. `/parseNotation?stepFields=fenString`.
+func requestAPI(r *http.Request) api.API {
+	return a.WithOptions(outputGameFieldsOptions(r.URL.Query().Get("fields"), r.URL.Query().Get("stepFields"))...)
+}
+
 //registers the handler as in http module docs
 func handleServerDefaultGame(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(a.DefaultGame())
+	json.NewEncoder(w).Encode(requestAPI(r).DefaultGame())
 }
 
 func handleCliDefaultGame() {
@@ -32,7 +53,7 @@ func handleServerParseGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
-	outputGame, err := a.ParseGame(ig)
+	outputGame, err := requestAPI(r).ParseGame(ig)
 	if err != nil {
 		fmt.Fprintln(w, formatError(err))
 		return
@@ -65,7 +86,7 @@ func handleServerDoAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
-	outputGame, outputAction, err := a.DoAction(input.Game, input.Action)
+	outputGame, outputAction, err := requestAPI(r).DoAction(input.Game, input.Action)
 	if err != nil {
 		fmt.Fprintln(w, formatError(err))
 		return
@@ -100,8 +121,10 @@ func handleCliDoAction(flagDoAction *string) {
 
 func handleServerParseNotation(w http.ResponseWriter, r *http.Request) {
 	type args struct {
-		Game           api.InputGame `json:"game"`
-		NotationString string        `json:"notationString"`
+		Game            api.InputGame               `json:"game"`
+		NotationString  string                      `json:"notationString"`
+		Notation        string                      `json:"notation"`
+		Characteristics api.NotationCharacteristics `json:"characteristics"`
 	}
 	var input args
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -109,7 +132,7 @@ func handleServerParseNotation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
-	outputGame, outputGameSteps, err := a.ParseNotation(input.Game, input.NotationString)
+	outputGame, outputGameSteps, outputNotation, err := requestAPI(r).ParseNotation(input.Game, input.NotationString, input.Notation, input.Characteristics)
 	if err != nil {
 		fmt.Fprintln(w, formatError(err))
 		return
@@ -117,20 +140,74 @@ func handleServerParseNotation(w http.ResponseWriter, r *http.Request) {
 	type out struct {
 		Game            api.OutputGame       `json:"game"`
 		OutputGameSteps []api.OutputGameStep `json:"outputGameSteps"`
+		Notation        api.OutputNotation   `json:"notation"`
 	}
-	json.NewEncoder(w).Encode(out{outputGame, outputGameSteps})
+	json.NewEncoder(w).Encode(out{outputGame, outputGameSteps, outputNotation})
 }
 
 func handleCliParseNotation(flagParseNotation *string) {
 	type args struct {
-		Game           api.InputGame `json:"game"`
-		NotationString string        `json:"notationString"`
+		Game            api.InputGame               `json:"game"`
+		NotationString  string                      `json:"notationString"`
+		Notation        string                      `json:"notation"`
+		Characteristics api.NotationCharacteristics `json:"characteristics"`
 	}
 	var input args
 	if err := json.Unmarshal([]byte(*flagParseNotation), &input); err != nil {
 		mustCliFatal(err)
 	}
-	outputGame, outputGameSteps, err := a.ParseNotation(input.Game, input.NotationString)
+	outputGame, outputGameSteps, outputNotation, err := a.ParseNotation(input.Game, input.NotationString, input.Notation, input.Characteristics)
+	if err != nil {
+		mustCliFatal(err)
+	}
+	type out struct {
+		Game            api.OutputGame       `json:"game"`
+		OutputGameSteps []api.OutputGameStep `json:"outputGameSteps"`
+		Notation        api.OutputNotation   `json:"notation"`
+	}
+	byts, _ := json.Marshal(out{outputGame, outputGameSteps, outputNotation})
+	fmt.Println(string(byts))
+}
+
+func handleServerConvertNotation(w http.ResponseWriter, r *http.Request) {
+	type args struct {
+		Game              api.InputGame               `json:"game"`
+		NotationString    string                      `json:"notationString"`
+		FromNotation      string                      `json:"fromNotation"`
+		ToNotation        string                      `json:"toNotation"`
+		ToCharacteristics api.NotationCharacteristics `json:"toCharacteristics"`
+	}
+	var input args
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	defer r.Body.Close()
+	outputGame, outputGameSteps, err := requestAPI(r).ConvertNotation(input.Game, input.NotationString, input.FromNotation, input.ToNotation, input.ToCharacteristics)
+	if err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	type out struct {
+		Game            api.OutputGame       `json:"game"`
+		OutputGameSteps []api.OutputGameStep `json:"outputGameSteps"`
+	}
+	json.NewEncoder(w).Encode(out{outputGame, outputGameSteps})
+}
+
+func handleCliConvertNotation(flagConvertNotation *string) {
+	type args struct {
+		Game              api.InputGame               `json:"game"`
+		NotationString    string                      `json:"notationString"`
+		FromNotation      string                      `json:"fromNotation"`
+		ToNotation        string                      `json:"toNotation"`
+		ToCharacteristics api.NotationCharacteristics `json:"toCharacteristics"`
+	}
+	var input args
+	if err := json.Unmarshal([]byte(*flagConvertNotation), &input); err != nil {
+		mustCliFatal(err)
+	}
+	outputGame, outputGameSteps, err := a.ConvertNotation(input.Game, input.NotationString, input.FromNotation, input.ToNotation, input.ToCharacteristics)
 	if err != nil {
 		mustCliFatal(err)
 	}
@@ -142,12 +219,186 @@ func handleCliParseNotation(flagParseNotation *string) {
 	fmt.Println(string(byts))
 }
 
+func handleServerParsePGN(w http.ResponseWriter, r *http.Request) {
+	type args struct {
+		PGNString string `json:"pgnString"`
+	}
+	var input args
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	defer r.Body.Close()
+	outputPGN, err := requestAPI(r).ParsePGN(input.PGNString)
+	if err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	json.NewEncoder(w).Encode(outputPGN)
+}
+
+func handleCliParsePGN(flagParsePGN *string) {
+	type args struct {
+		PGNString string `json:"pgnString"`
+	}
+	var input args
+	if err := json.Unmarshal([]byte(*flagParsePGN), &input); err != nil {
+		mustCliFatal(err)
+	}
+	outputPGN, err := a.ParsePGN(input.PGNString)
+	if err != nil {
+		mustCliFatal(err)
+	}
+	byts, _ := json.Marshal(outputPGN)
+	fmt.Println(string(byts))
+}
+
+func handleServerExportPGN(w http.ResponseWriter, r *http.Request) {
+	type args struct {
+		Game            api.InputGame        `json:"game"`
+		Actions         []api.InputAction    `json:"actions"`
+		OutputGameSteps []api.OutputGameStep `json:"outputGameSteps"`
+		Tags            []api.PGNTag         `json:"tags"`
+	}
+	var input args
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	defer r.Body.Close()
+	pgn, err := exportPGN(input.Game, input.Actions, input.OutputGameSteps, input.Tags)
+	if err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	type out struct {
+		PGNString string `json:"pgnString"`
+	}
+	json.NewEncoder(w).Encode(out{pgn})
+}
+
+func handleCliExportPGN(flagExportPGN *string) {
+	type args struct {
+		Game            api.InputGame        `json:"game"`
+		Actions         []api.InputAction    `json:"actions"`
+		OutputGameSteps []api.OutputGameStep `json:"outputGameSteps"`
+		Tags            []api.PGNTag         `json:"tags"`
+	}
+	var input args
+	if err := json.Unmarshal([]byte(*flagExportPGN), &input); err != nil {
+		mustCliFatal(err)
+	}
+	pgn, err := exportPGN(input.Game, input.Actions, input.OutputGameSteps, input.Tags)
+	if err != nil {
+		mustCliFatal(err)
+	}
+	type out struct {
+		PGNString string `json:"pgnString"`
+	}
+	byts, _ := json.Marshal(out{pgn})
+	fmt.Println(string(byts))
+}
+
+func handleServerPerft(w http.ResponseWriter, r *http.Request) {
+	type args struct {
+		Game  api.InputGame `json:"game"`
+		Depth int           `json:"depth"`
+	}
+	var input args
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	defer r.Body.Close()
+	outputPerft, err := a.Perft(input.Game, input.Depth)
+	if err != nil {
+		fmt.Fprintln(w, formatError(err))
+		return
+	}
+	json.NewEncoder(w).Encode(outputPerft)
+}
+
+func handleCliPerft(flagPerft *string) {
+	type args struct {
+		Game  api.InputGame `json:"game"`
+		Depth int           `json:"depth"`
+	}
+	var input args
+	if err := json.Unmarshal([]byte(*flagPerft), &input); err != nil {
+		mustCliFatal(err)
+	}
+	outputPerft, err := a.Perft(input.Game, input.Depth)
+	if err != nil {
+		mustCliFatal(err)
+	}
+	byts, _ := json.Marshal(outputPerft)
+	fmt.Println(string(byts))
+}
+
+func handleCliParsePGNFile(flagParsePGNFile *string) {
+	f, err := os.Open(*flagParsePGNFile)
+	if err != nil {
+		mustCliFatal(err)
+	}
+	defer f.Close()
+
+	// JSON Lines: one game (or the error parsing it) per line, told apart by their "type"
+	type gameLine struct {
+		Type string `json:"type"`
+		api.OutputPGN
+	}
+	type errorLine struct {
+		Type string `json:"type"`
+		api.PGNError
+	}
+	var (
+		w   = bufio.NewWriter(os.Stdout)
+		enc = json.NewEncoder(w)
+		s   = a.NewPGNScanner(f)
+	)
+	for s.Scan() {
+		outputPGN, err := s.Game()
+		var pgnError api.PGNError
+		switch {
+		case errors.As(err, &pgnError):
+			enc.Encode(errorLine{"error", pgnError})
+		case err != nil:
+			enc.Encode(errorLine{"error", api.PGNError{Message: err.Error()}})
+		default:
+			enc.Encode(gameLine{"game", outputPGN})
+		}
+	}
+	w.Flush()
+	if err := s.Err(); err != nil {
+		mustCliFatal(err)
+	}
+}
+
+// exportPGN uses the steps if supplied (e.g. to keep their comments), and the actions otherwise.
+func exportPGN(game api.InputGame, actions []api.InputAction, steps []api.OutputGameStep, tags []api.PGNTag) (string, error) {
+	if len(steps) > 0 {
+		return a.ExportPGNSteps(game, steps, tags)
+	}
+	return a.ExportPGN(game, actions, tags)
+}
+
 func mustCliFatal(err error) {
 	fmt.Println(formatError(err))
 	os.Exit(1)
 }
 
 func formatError(err error) string {
+	// Notation and PGN errors are structured, so that editors can point at the mistake
+	var notationError api.NotationError
+	if errors.As(err, &notationError) {
+		byts, _ := json.Marshal(notationError)
+		return string(byts)
+	}
+	var pgnError api.PGNError
+	if errors.As(err, &pgnError) {
+		byts, _ := json.Marshal(pgnError)
+		return string(byts)
+	}
 	errByts, _ := json.Marshal(err.Error())
 	return fmt.Sprintf(`{"error": %v}`, string(errByts))
 }
@@ -6,28 +6,46 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+
+	"github.com/marianogappa/cheesse/api"
 )
 
 var (
-	flagServe         = flag.Int("serve", 0, "Start a server on the specified port.")
-	flagDefaultGame   = flag.Bool("defaultGame", false, "Default API call. Returns a default game.")
-	flagParseGame     = flag.String("parseGame", "", "ParseGame API call. Requires a JSON string with arguments. Please review spec.")
-	flagDoAction      = flag.String("doAction", "", "DoAction API call. Requires a JSON string with arguments. Please review spec.")
-	flagParseNotation = flag.String("parseNotation", "", "ParseNotation API call. Requires a JSON string with arguments. Please review spec.")
+	flagServe           = flag.Int("serve", 0, "Start a server on the specified port.")
+	flagDefaultGame     = flag.Bool("defaultGame", false, "Default API call. Returns a default game.")
+	flagParseGame       = flag.String("parseGame", "", "ParseGame API call. Requires a JSON string with arguments. Please review spec.")
+	flagDoAction        = flag.String("doAction", "", "DoAction API call. Requires a JSON string with arguments. Please review spec.")
+	flagParseNotation   = flag.String("parseNotation", "", "ParseNotation API call. Requires a JSON string with arguments. Please review spec.")
+	flagConvertNotation = flag.String("convertNotation", "", "ConvertNotation API call. Requires a JSON string with arguments. Please review spec.")
+	flagParsePGN        = flag.String("parsePGN", "", "ParsePGN API call. Requires a JSON string with arguments. Please review spec.")
+	flagExportPGN       = flag.String("exportPGN", "", "ExportPGN API call. Requires a JSON string with arguments. Please review spec.")
+	flagParsePGNFile    = flag.String("parsePGNFile", "", "Parses every game in the specified PGN file. Outputs JSON Lines, with one game (or error) per line.")
+	flagPerft           = flag.String("perft", "", "Perft API call. Requires a JSON string with arguments. Please review spec.")
+	flagParallelActions = flag.Int("parallelActions", 0, "Calculate the actions of games in parallel, with at most the specified number of goroutines.")
+	flagSortedPieces    = flag.Bool("sortedPieceArrays", false, "Also describe the pieces of games as arrays sorted by square, i.e. blackPieceList and whitePieceList.")
+	flagFields          = flag.String("fields", "", "Only calculate and output the specified fields of games, e.g. fenString,actions. For the server, use the fields query parameter instead.")
+	flagStepFields      = flag.String("stepFields", "", "Like -fields, but for the games of every step except the last one, e.g. fenString. For the server, use the stepFields query parameter instead.")
 )
 
 func main() {
-
-	for _, s := range a.DefaultGame().Board.Board { // defaultGame returns output board which then contains board pieces
-		fmt.Println(s)
-	}
-
 	flag.Parse()
+	opts := []api.Option{api.WithParallelActions(*flagParallelActions)}
+	if *flagSortedPieces {
+		opts = append(opts, api.WithSortedPieceArrays())
+	}
+	if *flagServe == 0 {
+		opts = append(opts, outputGameFieldsOptions(*flagFields, *flagStepFields)...)
+	}
+	a = api.New(opts...)
 
 	http.HandleFunc("/parseGame", handleServerParseGame)
 	http.HandleFunc("/defaultGame", handleServerDefaultGame)
 	http.HandleFunc("/doAction", handleServerDoAction)
 	http.HandleFunc("/parseNotation", handleServerParseNotation)
+	http.HandleFunc("/convertNotation", handleServerConvertNotation)
+	http.HandleFunc("/parsePGN", handleServerParsePGN)
+	http.HandleFunc("/exportPGN", handleServerExportPGN)
+	http.HandleFunc("/perft", handleServerPerft)
 
 	switch {
 	case *flagServe != 0:
@@ -40,5 +58,15 @@ func main() {
 		handleCliDoAction(flagDoAction)
 	case *flagParseNotation != "":
 		handleCliParseNotation(flagParseNotation)
+	case *flagConvertNotation != "":
+		handleCliConvertNotation(flagConvertNotation)
+	case *flagParsePGN != "":
+		handleCliParsePGN(flagParsePGN)
+	case *flagExportPGN != "":
+		handleCliExportPGN(flagExportPGN)
+	case *flagParsePGNFile != "":
+		handleCliParsePGNFile(flagParsePGNFile)
+	case *flagPerft != "":
+		handleCliPerft(flagPerft)
 	}
 }
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"errors"
 	"syscall/js"
 	"github.com/marianogappa/cheesse/api"
 )
@@ -31,11 +32,70 @@ func DoAction(this js.Value, p []js.Value) interface{} {
 }
 
 func ParseNotation(this js.Value, p []js.Value) interface{} {
-	og, ogs, err := a.ParseNotation(convertToInputGame(p[0]), p[1].String())
+	var (
+		notation        string
+		characteristics api.NotationCharacteristics
+	)
+	if len(p) > 2 {
+		notation = jsString(p[2])
+	}
+	if len(p) > 3 {
+		characteristics = convertToNotationCharacteristics(p[3])
+	}
+	og, ogs, on, err := a.ParseNotation(convertToInputGame(p[0]), p[1].String(), notation, characteristics)
+	return js.ValueOf(map[string]interface{}{
+		"outputGame":      convertOutputGame(og),
+		"outputGameSteps": convertOutputGameSteps(ogs),
+		"notation":        convertOutputNotation(on),
+		"error":           convertError(err),
+		"notationError":   convertNotationError(err),
+	})
+}
+
+func ConvertNotation(this js.Value, p []js.Value) interface{} {
+	var toCharacteristics api.NotationCharacteristics
+	if len(p) > 4 {
+		toCharacteristics = convertToNotationCharacteristics(p[4])
+	}
+	og, ogs, err := a.ConvertNotation(convertToInputGame(p[0]), p[1].String(), jsString(p[2]), p[3].String(), toCharacteristics)
 	return js.ValueOf(map[string]interface{}{
 		"outputGame":      convertOutputGame(og),
 		"outputGameSteps": convertOutputGameSteps(ogs),
 		"error":           convertError(err),
+		"notationError":   convertNotationError(err),
+	})
+}
+
+func ParsePGN(this js.Value, p []js.Value) interface{} {
+	op, err := a.ParsePGN(p[0].String())
+	return js.ValueOf(map[string]interface{}{
+		"outputPGN": convertOutputPGN(op),
+		"error":     convertError(err),
+		"pgnError":  convertPGNError(err),
+	})
+}
+
+func ExportPGN(this js.Value, p []js.Value) interface{} {
+	actions := make([]api.InputAction, p[1].Length())
+	for i := range actions {
+		actions[i] = convertToInputAction(p[1].Index(i))
+	}
+	tags := make([]api.PGNTag, p[2].Length())
+	for i := range tags {
+		tags[i] = api.PGNTag{Name: jsString(p[2].Index(i).Get("name")), Value: jsString(p[2].Index(i).Get("value"))}
+	}
+	pgn, err := a.ExportPGN(convertToInputGame(p[0]), actions, tags)
+	return js.ValueOf(map[string]interface{}{
+		"pgnString": pgn,
+		"error":     convertError(err),
+	})
+}
+
+func Perft(this js.Value, p []js.Value) interface{} {
+	op, err := a.Perft(convertToInputGame(p[0]), p[1].Int())
+	return js.ValueOf(map[string]interface{}{
+		"outputPerft": convertOutputPerft(op),
+		"error":       convertError(err),
 	})
 }
 
@@ -44,6 +104,10 @@ func main() {
 	js.Global().Set("ParseGame", js.FuncOf(ParseGame))
 	js.Global().Set("DoAction", js.FuncOf(DoAction))
 	js.Global().Set("ParseNotation", js.FuncOf(ParseNotation))
+	js.Global().Set("ConvertNotation", js.FuncOf(ConvertNotation))
+	js.Global().Set("ParsePGN", js.FuncOf(ParsePGN))
+	js.Global().Set("ExportPGN", js.FuncOf(ExportPGN))
+	js.Global().Set("Perft", js.FuncOf(Perft))
 	select {}
 }
 
@@ -52,6 +116,15 @@ func convertToInputAction(v js.Value) api.InputAction {
 		FromSquare:         jsString(v.Get("fromSquare")),
 		ToSquare:           jsString(v.Get("toSquare")),
 		PromotionPieceType: jsString(v.Get("promotionPieceType")),
+		UCI:                jsString(v.Get("uci")),
+		SAN:                jsString(v.Get("san")),
+		IsResign:           jsBool(v.Get("isResign")),
+		IsClaimDraw:        jsBool(v.Get("isClaimDraw")),
+		IsTimeout:          jsBool(v.Get("isTimeout")),
+		IsOfferDraw:        jsBool(v.Get("isOfferDraw")),
+		IsAcceptDraw:       jsBool(v.Get("isAcceptDraw")),
+		IsDeclineDraw:      jsBool(v.Get("isDeclineDraw")),
+		IsAbort:            jsBool(v.Get("isAbort")),
 	}
 }
 
@@ -78,13 +151,57 @@ func convertToInputGame(v js.Value) api.InputGame {
 			Turn:                    jsString(board.Get("turn")),
 		}
 	}
+	var positionHistory []string
+	if ph := v.Get("positionHistory"); ph != js.Null() && ph != js.Undefined() {
+		positionHistory = make([]string, ph.Length())
+		for i := range positionHistory {
+			positionHistory[i] = jsString(ph.Index(i))
+		}
+	}
 	return api.InputGame{
-		DefaultGame: jsBool(v.Get("defaultGame")),
-		FENString:   jsString(v.Get("fenString")),
-		Board:       outerBoard,
+		DefaultGame:     jsBool(v.Get("defaultGame")),
+		FENString:       jsString(v.Get("fenString")),
+		Board:           outerBoard,
+		DrawOfferedBy:   jsString(v.Get("drawOfferedBy")),
+		PositionHistory: positionHistory,
 	}
 }
 
+func convertToNotationCharacteristics(v js.Value) api.NotationCharacteristics {
+	if v == js.Undefined() || v == js.Null() {
+		return api.NotationCharacteristics{}
+	}
+	var nc api.NotationCharacteristics
+	for name, symbol := range map[string]**string{
+		"checkSymbol":     &nc.CheckSymbol,
+		"checkmateSymbol": &nc.CheckmateSymbol,
+		"castlingSymbol":  &nc.CastlingSymbol,
+		"promotionSymbol": &nc.PromotionSymbol,
+		"endGameSymbol":   &nc.EndGameSymbol,
+	} {
+		if j := v.Get(name); j != js.Undefined() && j != js.Null() {
+			s := j.String()
+			*symbol = &s
+		}
+	}
+	for name, flag := range map[string]**bool{
+		"fullMoveDot":                &nc.FullMoveDot,
+		"newlineAsFullMoveSeparator": &nc.NewlineAsFullMoveSeparator,
+	} {
+		if j := v.Get(name); j != js.Undefined() && j != js.Null() {
+			b := j.Bool()
+			*flag = &b
+		}
+	}
+	if pieceLetters := v.Get("pieceLetters"); pieceLetters != js.Undefined() && pieceLetters != js.Null() {
+		nc.PieceLetters = make([]string, pieceLetters.Length())
+		for i := range nc.PieceLetters {
+			nc.PieceLetters[i] = jsString(pieceLetters.Index(i))
+		}
+	}
+	return nc
+}
+
 func jsBool(j js.Value) bool {
 	if j == js.Undefined() || j == js.Null() {
 		return false
@@ -113,6 +230,40 @@ func convertError(err error) string {
 	return err.Error()
 }
 
+// convertNotationError returns the details of a NotationError, or nil for any other error.
+func convertNotationError(err error) interface{} {
+	var ne api.NotationError
+	if !errors.As(err, &ne) {
+		return nil
+	}
+	return map[string]interface{}{
+		"offset":       ne.Offset,
+		"line":         ne.Line,
+		"column":       ne.Column,
+		"columnUTF16":  ne.ColumnUTF16,
+		"token":        ne.Token,
+		"ply":          ne.Ply,
+		"expected":     ne.Expected,
+		"alternatives": convertStringArr(ne.Alternatives),
+		"error":        ne.Message,
+	}
+}
+
+// convertPGNError returns the details of a PGNError, or nil for any other error.
+func convertPGNError(err error) interface{} {
+	var pe api.PGNError
+	if !errors.As(err, &pe) {
+		return nil
+	}
+	return map[string]interface{}{
+		"offset":      pe.Offset,
+		"line":        pe.Line,
+		"column":      pe.Column,
+		"columnUTF16": pe.ColumnUTF16,
+		"error":       pe.Message,
+	}
+}
+
 func convertOutputGameSteps(ogs []api.OutputGameStep) []interface{} {
 	is := make([]interface{}, len(ogs))
 	for i := range ogs {
@@ -122,10 +273,60 @@ func convertOutputGameSteps(ogs []api.OutputGameStep) []interface{} {
 }
 
 func convertOutputGameStep(ogs api.OutputGameStep) map[string]interface{} {
+	variations := make([]interface{}, len(ogs.Variations))
+	for i := range ogs.Variations {
+		variations[i] = convertOutputGameSteps(ogs.Variations[i])
+	}
 	return map[string]interface{}{
 		"game":         convertOutputGame(ogs.Game),
 		"action":       convertOutputAction(ogs.Action),
 		"actionString": ogs.ActionString,
+		"comments":     convertStringArr(ogs.Comments),
+		"nags":         convertIntArr(ogs.NAGs),
+		"variations":   variations,
+	}
+}
+
+func convertOutputPGN(op api.OutputPGN) map[string]interface{} {
+	tags := make([]interface{}, len(op.Tags))
+	for i := range op.Tags {
+		tags[i] = map[string]interface{}{"name": op.Tags[i].Name, "value": op.Tags[i].Value}
+	}
+	return map[string]interface{}{
+		"tags":            tags,
+		"game":            convertOutputGame(op.Game),
+		"outputGameSteps": convertOutputGameSteps(op.OutputGameSteps),
+		"comments":        convertStringArr(op.Comments),
+		"result":          op.Result,
+	}
+}
+
+func convertOutputNotation(on api.OutputNotation) map[string]interface{} {
+	ch := on.Characteristics
+	characteristics := map[string]interface{}{}
+	for name, symbol := range map[string]*string{
+		"checkSymbol":     ch.CheckSymbol,
+		"checkmateSymbol": ch.CheckmateSymbol,
+		"castlingSymbol":  ch.CastlingSymbol,
+		"promotionSymbol": ch.PromotionSymbol,
+		"endGameSymbol":   ch.EndGameSymbol,
+	} {
+		if symbol != nil {
+			characteristics[name] = *symbol
+		}
+	}
+	if ch.FullMoveDot != nil {
+		characteristics["fullMoveDot"] = *ch.FullMoveDot
+	}
+	if ch.NewlineAsFullMoveSeparator != nil {
+		characteristics["newlineAsFullMoveSeparator"] = *ch.NewlineAsFullMoveSeparator
+	}
+	if ch.PieceLetters != nil {
+		characteristics["pieceLetters"] = convertStringArr(ch.PieceLetters)
+	}
+	return map[string]interface{}{
+		"notation":        on.Notation,
+		"characteristics": characteristics,
 	}
 }
 
@@ -134,6 +335,7 @@ func convertOutputGame(og api.OutputGame) map[string]interface{} {
 		"fenString":               og.FENString,
 		"board":                   convertBoard(og.Board),
 		"actions":                 convertOutputActions(og.Actions),
+		"nonMoveActions":          convertOutputActions(og.NonMoveActions),
 		"canWhiteCastle":          og.CanWhiteCastle,
 		"canWhiteKingsideCastle":  og.CanWhiteKingsideCastle,
 		"canWhiteQueensideCastle": og.CanWhiteQueensideCastle,
@@ -147,6 +349,8 @@ func convertOutputGame(og api.OutputGame) map[string]interface{} {
 		"moveNumber":              og.MoveNumber,
 		"blackPieces":             convertMapStringToString(og.BlackPieces),
 		"whitePieces":             convertMapStringToString(og.WhitePieces),
+		"blackPieceList":          convertOutputPieces(og.BlackPieceList),
+		"whitePieceList":          convertOutputPieces(og.WhitePieceList),
 		"blackKing":               og.BlackKing,
 		"whiteKing":               og.WhiteKing,
 		"isCheck":                 og.IsCheck,
@@ -156,6 +360,18 @@ func convertOutputGame(og api.OutputGame) map[string]interface{} {
 		"isGameOver":              og.IsGameOver,
 		"gameOverWinner":          og.GameOverWinner,
 		"inCheckBy":               convertStringArr(og.InCheckBy),
+		"repetitionCount":         og.RepetitionCount,
+		"isThreefoldRepetition":   og.IsThreefoldRepetition,
+		"isFivefoldRepetition":    og.IsFivefoldRepetition,
+		"canClaimDraw":            og.CanClaimDraw,
+		"claimDrawReasons":        convertStringArr(og.ClaimDrawReasons),
+		"gameOverReason":          og.GameOverReason,
+		"canWhiteWinOnTime":       og.CanWhiteWinOnTime,
+		"canBlackWinOnTime":       og.CanBlackWinOnTime,
+		"result":                  og.Result,
+		"drawOfferedBy":           og.DrawOfferedBy,
+		"positionHash":            og.PositionHash,
+		"positionHistory":         convertStringArr(og.PositionHistory),
 	}
 }
 
@@ -167,6 +383,14 @@ func convertStringArr(ss []string) []interface{} {
 	return is
 }
 
+func convertIntArr(is []int) []interface{} {
+	vs := make([]interface{}, len(is))
+	for i := 0; i < len(is); i++ {
+		vs[i] = is[i]
+	}
+	return vs
+}
+
 func convertMapStringToString(mss map[string]string) map[string]interface{} {
 	m := make(map[string]interface{}, len(mss))
 	for k, v := range mss {
@@ -175,6 +399,39 @@ func convertMapStringToString(mss map[string]string) map[string]interface{} {
 	return m
 }
 
+func convertOutputPerft(op api.OutputPerft) map[string]interface{} {
+	divide := make([]interface{}, len(op.Divide))
+	for i, d := range op.Divide {
+		m := convertPerftCounts(d.PerftCounts)
+		m["action"] = convertOutputAction(d.Action)
+		m["actionString"] = d.ActionString
+		divide[i] = m
+	}
+	m := convertPerftCounts(op.PerftCounts)
+	m["divide"] = divide
+	return m
+}
+
+func convertPerftCounts(c api.PerftCounts) map[string]interface{} {
+	return map[string]interface{}{
+		"nodes":      c.Nodes,
+		"captures":   c.Captures,
+		"enPassants": c.EnPassants,
+		"castles":    c.Castles,
+		"promotions": c.Promotions,
+		"checks":     c.Checks,
+		"checkmates": c.Checkmates,
+	}
+}
+
+func convertOutputPieces(ops []api.OutputPiece) []interface{} {
+	is := make([]interface{}, len(ops))
+	for i := range ops {
+		is[i] = map[string]interface{}{"square": ops[i].Square, "pieceType": ops[i].PieceType}
+	}
+	return is
+}
+
 func convertOutputActions(as []api.OutputAction) []interface{} {
 	is := make([]interface{}, len(as))
 	for i := range as {
@@ -192,6 +449,12 @@ func convertOutputAction(a api.OutputAction) map[string]interface{} {
 		"toSquare":           a.ToSquare,
 		"isCapture":          a.IsCapture,
 		"isResign":           a.IsResign,
+		"isClaimDraw":        a.IsClaimDraw,
+		"isTimeout":          a.IsTimeout,
+		"isOfferDraw":        a.IsOfferDraw,
+		"isAcceptDraw":       a.IsAcceptDraw,
+		"isDeclineDraw":      a.IsDeclineDraw,
+		"isAbort":            a.IsAbort,
 		"isPromotion":        a.IsPromotion,
 		"isEnPassant":        a.IsEnPassant,
 		"isEnPassantCapture": a.IsEnPassantCapture,
@@ -200,6 +463,9 @@ func convertOutputAction(a api.OutputAction) map[string]interface{} {
 		"isQueensideCastle":  a.IsQueensideCastle,
 		"promotionPieceType": a.PromotionPieceType,
 		"capturedPieceType":  a.CapturedPieceType,
+		"uci":                a.UCI,
+		"san":                a.SAN,
+		"lan":                a.LAN,
 	}
 }
 